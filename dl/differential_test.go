@@ -0,0 +1,45 @@
+package dl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// Test__libdl_cross_shell checks that functions which do not depend on
+// shell features behave the same in all the shells.
+func Test__libdl_cross_shell(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+		args   []string
+	}{
+		{name: "GOARM", script: `__libdl_GOARM "$@"`, args: []string{"armv7l", "armv6", "armv8b", "aarch64", "FOO-BAR", ""}},
+		{name: "GOARCH", script: `__libdl_GOARCH "$@"`, args: []string{"x86_64", "i686", "armv7l", "aarch64", "ppc64le", "FOO-BAR"}},
+		{
+			name:   "render_template",
+			script: `__libdl_render_template "$@"`,
+			args: []string{
+				"https://example.com/v1/file_++GOOS++_++GOARCH++.tar.gz",
+				"https://example.com/v1/file_++SYS_OS++_++SYS_ARCH++.tar.gz",
+				"https://example.com/v1/file.tar.gz",
+				"",
+			},
+		},
+		{name: "shlib_explain_error", script: `shlib_explain_error "$@"`, args: []string{"0", "1", "3", "11", "22", "27", "81", "127", "200", "foo"}},
+	}
+
+	for _, tc := range tests {
+		for _, arg := range tc.args {
+			t.Run(fmt.Sprintf("%s=%s", tc.name, arg), func(t *testing.T) {
+				libtest.Differential(t, []string{"dl"}, tc.script,
+					libtest.WithArgs(arg),
+					libtest.WithEnv("TZ=UTC"),
+				)
+			})
+		}
+	}
+}
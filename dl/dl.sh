@@ -29,12 +29,12 @@ shlib_explain_error() {
     case ${err_code} in
     0) ;;
     # We should never return 1 or 127
-    1 | 127) printf "[ERROR ] An unhandled exception occured!\n" ;;
+    1 | 127) printf "[ERROR ] An unhandled exception occured!\n" >&2 ;;
     # Assume we do not have logging functions available either.
     2)
-        printf "[ERROR ] Dependency Error.\n"
-        printf "[ERROR ] This script requires logger library from https://github.com/tprasadtp/shlibs/logger\n"
-        printf "[ERROR ] Please source it or embed it in file before using dl library.\n"
+        printf "[ERROR ] Dependency Error.\n" >&2
+        printf "[ERROR ] This script requires logger library from https://github.com/tprasadtp/shlibs/logger\n" >&2
+        printf "[ERROR ] Please source it or embed it in file before using dl library.\n" >&2
         ;;
     3)
         log_error "Invalid, Unsupported or not enough arguments"
@@ -45,22 +45,24 @@ shlib_explain_error() {
     # Token/Auth Errors
     5) log_error "Authorization token specified is empty" ;;
     6) log_error "Bearer token specified is empty" ;;
+    # Not returned: duplicate of 51.
     8) log_error "Output destination is not writable" ;;
+    # Not returned: duplicate of 19.
     9) log_error "GPG Key specified is invalid" ;;
     11)
         log_error "Failed to detect and map GOOS/GOARCH/GOARM!"
-        __libdl_repo _error_helper
+        __libdl_report_error_helper
         ;;
     12)
         log_error "Internal Error! Invalid arguments!"
         ;;
     14)
         log_error "Failed to determine system architecture or os."
-        __libdl_repo _error_helper
+        __libdl_report_error_helper
         ;;
     15)
         log_error "Failed to determine verificataion handler."
-        __libdl_repo _error_helper
+        __libdl_report_error_helper
         ;;
     # URL user errors
     16) log_error "File URL specified is invalid. (URL MUST start with http:// or https://)" ;;
@@ -68,6 +70,7 @@ shlib_explain_error() {
         log_error "Checksum specified is invalid. (URL MUST start with http:// or https://)"
         log_error "If checksum is directly specified, ensure that it matches the algorithm specified."
         ;;
+    # Not returned: unreadable signatures are reported as 42.
     18)
         log_error "Signature specified is invalid."
         log_error "If GPG signature is URL, it MUST start with http:// or https://"
@@ -92,12 +95,12 @@ shlib_explain_error() {
     22)
         log_error "Cannot find any of: sha256sum, gsha256sum, or shasum, required for SHA256 checksum verification."
         # shellcheck disable=SC2155
-        local __libdl_errmap_os="$(libdl_get_GOOS)"
+        local __libdl_errmap_os="$(__libdl_GOOS)"
         case $__libdl_errmap_os in
-        Linux)
+        linux)
             log_error "sha256sum is provided by package coreutils. Install it via package manager."
             ;;
-        Darwin)
+        darwin)
             log_error "sha256sum is not available by default, install coreutils via Homebrew"
             ;;
         esac
@@ -106,12 +109,12 @@ shlib_explain_error() {
     23)
         log_error "Cannot find any of: sha512sum, gsha512sum or shasum, required for SHA512 checksum verification."
         # shellcheck disable=SC2155
-        local __libdl_errmap_os="$(libdl_get_GOOS)"
+        local __libdl_errmap_os="$(__libdl_GOOS)"
         case $__libdl_errmap_os in
-        Linux)
+        linux)
             log_error "sha512sum is provided by package coreutils. Install it via package manager."
             ;;
-        Darwin)
+        darwin)
             log_error "sha512sum is not available by default, install coreutils via Homebrew"
             ;;
         esac
@@ -120,12 +123,12 @@ shlib_explain_error() {
     24)
         log_error "Cannot any any of: sha1sum, gsha1sum or shasum, Required for SHA1 checksum verification."
         # shellcheck disable=SC2155
-        local __libdl_errmap_os="$(libdl_get_GOOS)"
+        local __libdl_errmap_os="$(__libdl_GOOS)"
         case $__libdl_errmap_os in
-        Linux)
+        linux)
             log_error "sha1sum is provided by package coreutils or busybox. Install it via package manager."
             ;;
-        Darwin)
+        darwin)
             log_error "sha1sum should be available by default. Alternatively install coreutils via Homebrew"
             ;;
         esac
@@ -134,12 +137,12 @@ shlib_explain_error() {
     25)
         log_error "Cannot any any of: md5sum or gmd5sum, Required for MD5 checksum verification."
         # shellcheck disable=SC2155
-        local __libdl_errmap_os="$(libdl_get_GOOS)"
+        local __libdl_errmap_os="$(__libdl_GOOS)"
         case $__libdl_errmap_os in
-        Linux)
+        linux)
             log_error "md5sum is provided by package coreutils or busybox. Install it via package manager."
             ;;
-        Darwin)
+        darwin)
             log_error "md5sum should be available by default. Alternatively install coreutils via Homebrew"
             ;;
         esac
@@ -148,12 +151,26 @@ shlib_explain_error() {
     26)
         log_error "Cannot find command gpg which is required for signature verification."
         # shellcheck disable=SC2155
-        local __libdl_errmap_os="$(libdl_get_GOOS)"
+        local __libdl_errmap_os="$(__libdl_GOOS)"
         case $__libdl_errmap_os in
-        Linux)
+        linux)
             log_error "Command gpg is usually provided by package gnupg or gpg. Install it via package manager."
             ;;
-        Darwin)
+        darwin)
+            log_error "Please install gnupg via Homebrew - brew install gnupg"
+            ;;
+        esac
+        ;;
+
+    27)
+        log_error "Cannot find any of: gpg or gpgv, required for signature verification."
+        # shellcheck disable=SC2155
+        local __libdl_errmap_os="$(__libdl_GOOS)"
+        case $__libdl_errmap_os in
+        linux)
+            log_error "Commands gpg and gpgv are usually provided by package gnupg or gpg. Install it via package manager."
+            ;;
+        darwin)
             log_error "Please install gnupg via Homebrew - brew install gnupg"
             ;;
         esac
@@ -162,21 +179,26 @@ shlib_explain_error() {
     31) log_error "Target file not found or not accesible." ;;
     32) log_error "Checksum file was not found or not accessible." ;;
     33) log_error "Failed to caclulate checksum for unknown reasons" ;;
+    # Not returned: duplicate of 17.
     34) log_error "Checksum hash is invalid" ;;
     35) log_error "Checksum file is missing hashes for the target specified or is invalid" ;;
     36) log_error "Unsupported hash algorithm. Only md5, sha1, sha256 and sha512 are supported" ;;
+    # Not returned: hashes missing from remote checksum files are reported as 35.
     37) log_error "Remote endpoint returned invalid hash!" ;;
 
     # Signature errors
     41) log_error "Target file not found or not accesible." ;;
     42) log_error "Signature file was not found or not accessible." ;;
     43) log_error "Keyring file specified was not or not accessible" ;;
+    # Not returned: all failures of gpg are reported as 81.
     44) log_error "Failed to verify signature for unknown reasons" ;;
 
     # Path errors
     50) log_error "Temp dir is not writable or tempdir creation failed" ;;
     51) log_error "Destination file/directory is not writable" ;;
+    # Not returned: missing destination directory is reported as not writable.
     52) log_error "Destination directory does not exist" ;;
+    # Not returned: existing destination is reported as 103.
     53) log_error "Destination file exists but checksum verification is not enabled, Must use --overwrite or --force" ;;
 
     # Download Errors
@@ -185,10 +207,12 @@ shlib_explain_error() {
     62) log_error "Checksum verification was enabled via remote file, but failed to fetch it after multiple attempts!" ;;
     63) log_error "GPG singature verification was enabled remote key file, but failed to fetch it after multiple attempts!" ;;
     64) log_error "GPG singature verification was enabled remote signature file, but failed to fetch it after multiple attempts!" ;;
+    # Not returned: responses are not parsed.
     68)
         log_error "Remote endpoint retuned empty response!"
         log_error "If its a bug in the parser please report this error at github.com/tprasadtp/shlibs"
         ;;
+    # Not returned: duplicate of 16.
     70) log_error "Remote data URL is invalid or not supported!" ;;
     72) log_error "Downloading file failed! Please verify that the URL is accessible with correct credentials." ;;
 
@@ -647,7 +671,13 @@ __libdl_hash_sha512() {
 # return 0 if true 1 otherwise
 __libdl_is_md5hash() {
     local hash="${1}"
-    if printf "%s" "$hash" | grep -qE '^[a-f0-9]{32}$'; then
+    # Checked without grep, which would match any line of multi-line input.
+    case $hash in
+    *[!a-f0-9]*)
+        return 1
+        ;;
+    esac
+    if [ "${#hash}" -eq 32 ]; then
         return 0
     else
         return 1
@@ -658,7 +688,12 @@ __libdl_is_md5hash() {
 # return 0 if true 1 otherwise
 __libdl_is_sha1hash() {
     local hash="${1}"
-    if printf "%s" "$hash" | grep -qE '^[a-f0-9]{40}$'; then
+    case $hash in
+    *[!a-f0-9]*)
+        return 1
+        ;;
+    esac
+    if [ "${#hash}" -eq 40 ]; then
         return 0
     else
         return 1
@@ -669,7 +704,12 @@ __libdl_is_sha1hash() {
 # return 0 if true 1 otherwise
 __libdl_is_sha256hash() {
     local hash="${1}"
-    if printf "%s" "$hash" | grep -qE '^[a-f0-9]{64}$'; then
+    case $hash in
+    *[!a-f0-9]*)
+        return 1
+        ;;
+    esac
+    if [ "${#hash}" -eq 64 ]; then
         return 0
     else
         return 1
@@ -680,7 +720,12 @@ __libdl_is_sha256hash() {
 # return 0 if true 1 otherwise
 __libdl_is_sha512hash() {
     local hash="${1}"
-    if printf "%s" "$hash" | grep -qE '^[a-f0-9]{128}$'; then
+    case $hash in
+    *[!a-f0-9]*)
+        return 1
+        ;;
+    esac
+    if [ "${#hash}" -eq 128 ]; then
         return 0
     else
         return 1
@@ -907,7 +952,7 @@ __libdl_hash_verify() {
 # Render URL
 __libdl_render_template() {
     local url="${1}"
-    local goos goarch goarm version uname_s uname_m
+    local goos goarch goarm uname_s uname_m
 
     # Template rendering:GOOS
     case $url in
@@ -955,7 +1000,7 @@ __libdl_render_template() {
     *++SYS_ARCH++*)
         uname_m="$(uname -m)"
         if [ -n "$uname_m" ]; then
-            url="$(printf "%s" "${url}" | sed -e "s/++UNAME_M++/${uname_m}/g")"
+            url="$(printf "%s" "${url}" | sed -e "s/++SYS_ARCH++/${uname_m}/g")"
         else
             return 14
         fi
@@ -967,7 +1012,7 @@ __libdl_render_template() {
     *++SYS_OS++*)
         uname_s="$(uname -s)"
         if [ -n "$uname_s" ]; then
-            url="$(printf "%s" "${url}" | sed -e "s/++UNAME_S++/${uname_s}/g")"
+            url="$(printf "%s" "${url}" | sed -e "s/++SYS_OS++/${uname_s}/g")"
         else
             return 14
         fi
@@ -1109,8 +1154,7 @@ __libdl_dl_asset() {
     fi
 
     local url output
-    local http_code
-    local auth_header
+    local auth_headers
     local user_agent
 
     url="${1}"
@@ -1172,15 +1216,25 @@ __libdl_dl_asset() {
     # we do not add a progress bar and other fancy features.
     # as wget may be from busybox. may be we should detect that,
     # and handle it?
-    elif __libdl_has_wget; then
+    elif __libdl_has_wget && test -n "${auth_headers}"; then
         if wget -q --tries=5 \
             -U "${user_agent}" \
-            "${auth_header_args}" \
-            --header "${auth_header_args}" \
+            --header "${auth_headers}" \
             --output-document "${output}" "${url}"; then
-            log_trace "asset download successful"
+            log_trace "asset download successful (with auth)"
             return 0
         else
+            log_trace "asset download failed (with auth)"
+            return 61
+        fi
+    elif __libdl_has_wget && test -z "${auth_headers}"; then
+        if wget -q --tries=5 \
+            -U "${user_agent}" \
+            --output-document "${output}" "${url}"; then
+            log_trace "asset download successful (without auth)"
+            return 0
+        else
+            log_trace "asset download failed (without auth)"
             return 61
         fi
     else
@@ -1189,6 +1243,14 @@ __libdl_dl_asset() {
     fi
 }
 
+# Removes temporary working directory of shlib_download_file.
+# This is used on errors, thus failures are only logged.
+__libdl_rm_wdir() {
+    if ! rm -rf "$1"; then
+        log_warning "Failed to remove temporary directory - $1"
+    fi
+}
+
 __libdl_dl_help() {
     cat <<EOF
 shlib/dl (shlib-id: 8a45258c-4346-4cc6-9c98-d2dac5446124)
@@ -1239,7 +1301,7 @@ shlib_download_file() {
         return 3
     fi
 
-    local remote_url local_file
+    local remote_url
 
     local checksum
     local checksum_algo
@@ -1247,6 +1309,7 @@ shlib_download_file() {
     local gpg_signature
 
     local gpg_key
+    local gpg_keyring
 
     local user_agent="shlib/dl/v1"
     local user_agent_override="0"
@@ -1259,7 +1322,6 @@ shlib_download_file() {
     local bearer_token
     local bearer_token_enable="0"
 
-    local extra_headers
     local auth_header
 
     # Lack of support for short options is intentional
@@ -1296,7 +1358,6 @@ shlib_download_file() {
             gpg_key="${1}"
             ;;
         --force)
-            shift
             force=1
             ;;
         --auth-token)
@@ -1469,10 +1530,12 @@ shlib_download_file() {
                 # check for local file
                 elif [ -f "$gpg_key" ] && [ -r "$gpg_key" ]; then
                     gpg_key_download="0"
+                    gpg_keyring="${gpg_key}"
                     log_debug "GPG Key             : ${gpg_key}"
                     log_debug "GPG Key Type        : Local File"
                 elif [ -L "$gpg_key" ]; then
                     log_error "GPG Key cannot be a symlink - ${gpg_key}"
+                    return 19
                 else
                     log_error "GPG Key was not found or is not readable - ${gpg_key}"
                     return 43
@@ -1488,7 +1551,6 @@ shlib_download_file() {
     # local gpg signature.
     # --------------------------------------------------------------------------
     local gpg_sig_download="0"
-    local gpg_verifiy="0"
 
     if test -z "$gpg_signature"; then
         log_debug "No signature specified, skipped gpg signature verification"
@@ -1512,7 +1574,7 @@ shlib_download_file() {
         *)
             if [ -f "$gpg_signature" ] && [ -r "$gpg_signature" ]; then
                 gpg_sig_download="0"
-                "GPG Signature       : ${gpg_signature}"
+                log_debug "GPG Signature       : ${gpg_signature}"
                 log_debug "GPG Signature Type  : Local File"
             else
                 log_error "GPG Signature file ${gpg_signature} was not found or not readable!"
@@ -1527,7 +1589,6 @@ shlib_download_file() {
     # local checksum file or just raw checksum
     # --------------------------------------------------------------------------
     local checksum_download="0"
-    local checksum_verifiy="0"
 
     if test -z "$checksum"; then
         log_debug "No checksum specified, skipped checksum verification"
@@ -1547,7 +1608,7 @@ shlib_download_file() {
             checksum_algo="md5"
             ;;
         *)
-            log_error "Unsupported hash algorithm - ${algorithm}"
+            log_error "Unsupported hash algorithm - ${checksum_algo}"
             return 36
             ;;
         esac
@@ -1562,7 +1623,7 @@ shlib_download_file() {
             rendered_checksum_url_rc="$?"
             if [ "${rendered_checksum_url_rc}" -ne 0 ]; then
                 log_error "Failed to render URL - ${checksum}"
-                return "${rendered_url_rc}"
+                return "${rendered_checksum_url_rc}"
             elif test -z "$checksum"; then
                 log_error "Rendered checksum URL is empty! Did you specify --checksum parameter correctly?"
                 return 3
@@ -1615,8 +1676,14 @@ shlib_download_file() {
             # set local keyring
             gpg_keyring="${temp_wdir}/gpg.keys"
             ;;
-        61) return 63 ;;
-        *) return ${dl_gpg_key_rc}
+        61)
+            __libdl_rm_wdir "${temp_wdir}"
+            return 63
+            ;;
+        *)
+            __libdl_rm_wdir "${temp_wdir}"
+            return ${dl_gpg_key_rc}
+            ;;
 
         esac
     else
@@ -1632,6 +1699,7 @@ shlib_download_file() {
         dl_gpg_sig_rc="$?"
 
         if [ "${dl_gpg_sig_rc}" -ne 0 ]; then
+            __libdl_rm_wdir "${temp_wdir}"
             return 64
         else
             log_trace "Setting signature to downloaded key file"
@@ -1651,6 +1719,7 @@ shlib_download_file() {
         dl_checksum_rc="$?"
 
         if [ "${dl_checksum_rc}" -ne 0 ]; then
+            __libdl_rm_wdir "${temp_wdir}"
             return 62
         else
             log_trace "Setting checksum to downloaded file"
@@ -1669,9 +1738,11 @@ shlib_download_file() {
     rendered_url_rc="$?"
     if [ "${rendered_url_rc}" -ne 0 ]; then
         log_error "Failed to render URL - ${remote_url}"
+        __libdl_rm_wdir "${temp_wdir}"
         return "${rendered_url_rc}"
     elif test -z "$rendered_url"; then
         log_error "Rendered URL is empty! Did you specify --url parameter correctly?"
+        __libdl_rm_wdir "${temp_wdir}"
         return 3
     fi
 
@@ -1684,26 +1755,29 @@ shlib_download_file() {
 
     # Abort if dl failed
     if [ "${dl_asset_rc}" != "0" ]; then
+        __libdl_rm_wdir "${temp_wdir}"
         return 72
     fi
 
     # gpg verification
-    local gpg_verifiy_rc
+    local gpg_verify_rc
     if test -n "${gpg_signature}"; then
         # check if checksum is specified, if so, gpg signature is verified for checksum file
         if test -n "${checksum}"; then
             log_info "Verifying : GPG signature of checksum file"
-            __libdl_gpg_verify "${gpg_signature}" "${checksum}" "${gpg_keyring}"
-            gpg_verifiy_rc="$?"
-            log_trace "GPG verify returned - ${gpg_verifiy_rc}"
+            __libdl_gpg_verify "${checksum}" "${gpg_signature}" "${gpg_keyring}"
+            gpg_verify_rc="$?"
+            log_trace "GPG verify returned - ${gpg_verify_rc}"
             if [ "${gpg_verify_rc}" != "0" ]; then
+                __libdl_rm_wdir "${temp_wdir}"
                 return ${gpg_verify_rc}
             fi
         else
             log_info "Verifying : GPG signature of asset file"
-            __libdl_gpg_verify "${gpg_signature}" "${temp_wdir}/${dl_asset_basename}" "${gpg_keyring}"
-            gpg_verifiy_rc="$?"
+            __libdl_gpg_verify "${temp_wdir}/${dl_asset_basename}" "${gpg_signature}" "${gpg_keyring}"
+            gpg_verify_rc="$?"
             if [ "${gpg_verify_rc}" != "0" ]; then
+                __libdl_rm_wdir "${temp_wdir}"
                 return "${gpg_verify_rc}"
             fi
         fi
@@ -1718,6 +1792,7 @@ shlib_download_file() {
         __libdl_hash_verify "${temp_wdir}/${dl_asset_basename}" "${checksum}" "${checksum_algo}"
         hash_rc="$?"
         if [ "${hash_rc}" -ne 0 ]; then
+            __libdl_rm_wdir "${temp_wdir}"
             return "${hash_rc}"
         fi
     else
@@ -1727,31 +1802,35 @@ shlib_download_file() {
     # check if destinaton file exists
     if [ -e "${output_file}" ]; then
         if [ "${force}" -eq 1 ]; then
-            if [ -f "${output}" || -L "${output_file}" ]; then
+            if [ -f "${output_file}" ] || [ -L "${output_file}" ]; then
                 log_debug "Removing existing file -${output_file}"
                 if rm "${output_file}"; then
                     log_debug "Unlinked ${output_file}"
                 else
+                    __libdl_rm_wdir "${temp_wdir}"
                     return 100
                 fi
             else
                 log_error "--force can only overwrite files and symnlinks!"
+                __libdl_rm_wdir "${temp_wdir}"
                 return 3
             fi
         else
-            log_error "${output} already exists, use --force to overwrite it"
+            log_error "${output_file} already exists, use --force to overwrite it"
+            __libdl_rm_wdir "${temp_wdir}"
             return 103
         fi
+    fi
+
+    if mv "${temp_wdir}/${dl_asset_basename}" "${output_file}"; then
+        log_debug "Copied downloaded file to ${output_file}"
     else
-        if mv "${temp_wdir}/${dl_asset_basename}" "${output_file}"; then
-            log_debug "Copied downloaded file to ${output_file}"
-        else
-            return 111
-        fi
+        __libdl_rm_wdir "${temp_wdir}"
+        return 111
     fi
 
     log_trace "Cleanup temporary files"
-    if rm -rf /tmp/"$(dirname "${temp_wdir}")"; then
+    if rm -rf "${temp_wdir}"; then
         log_debug "Cleanup complete"
     else
         return 101
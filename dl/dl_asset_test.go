@@ -0,0 +1,109 @@
+package dl
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func Test__libdl_dl_asset_curl(t *testing.T) {
+	// t.Parallel()
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+		code   int
+	}{
+		{name: "with-auth", auth: "Authorization: token foo"},
+		{name: "without-auth"},
+		{name: "with-auth-failed", auth: "Authorization: token foo", status: 22, code: 61},
+		{name: "without-auth-failed", status: 22, code: 61},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.code), func(t *testing.T) {
+				sb := libtest.NewSandbox(t, "dirname")
+				curl := sb.FakeCommand("curl",
+					libtest.FakeBehavior{Args: []string{"--version"}, Stdout: "curl 7.88.1\n"},
+					libtest.FakeBehavior{ExitCode: tc.status},
+				)
+				output := filepath.Join(sb.Dir(), "asset.tar.gz")
+
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_dl_asset "$@"`,
+					libtest.WithSandbox(sb),
+					libtest.WithArgs("https://example.com/asset.tar.gz", output, tc.auth),
+				)
+
+				assert.Equal(t, tc.code, r.ExitCode)
+				assert.Empty(t, r.Stdout)
+				assert.Empty(t, r.Stderr)
+
+				var downloads []libtest.FakeCall
+				for _, call := range curl.Calls() {
+					if !call.HasArgs("--version") {
+						downloads = append(downloads, call)
+					}
+				}
+				if !assert.Len(t, downloads, 1) {
+					return
+				}
+
+				call := downloads[0]
+				assert.True(t, call.HasArgs("--retry", "5"), "args: %q", call.Args)
+				assert.True(t, call.HasArgs("--user-agent", "shlib/dl/v1"), "args: %q", call.Args)
+				assert.True(t, call.HasArgs("--output", output, "https://example.com/asset.tar.gz"), "args: %q", call.Args)
+				assert.Equal(t, tc.auth != "", call.HasArgs("--header", tc.auth), "args: %q", call.Args)
+			})
+		}
+	}
+}
+
+func Test__libdl_dl_asset_wget(t *testing.T) {
+	// t.Parallel()
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+		code   int
+	}{
+		{name: "with-auth", auth: "Authorization: token foo"},
+		{name: "without-auth"},
+		{name: "with-auth-failed", auth: "Authorization: token foo", status: 8, code: 61},
+		{name: "without-auth-failed", status: 8, code: 61},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.code), func(t *testing.T) {
+				// curl is not available, so that wget is used.
+				sb := libtest.NewSandbox(t, "dirname")
+				wget := sb.FakeCommand("wget", libtest.FakeBehavior{ExitCode: tc.status})
+				output := filepath.Join(sb.Dir(), "asset.tar.gz")
+
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_dl_asset "$@"`,
+					libtest.WithSandbox(sb),
+					libtest.WithArgs("https://example.com/asset.tar.gz", output, tc.auth),
+				)
+
+				assert.Equal(t, tc.code, r.ExitCode)
+				assert.Empty(t, r.Stdout)
+				assert.Empty(t, r.Stderr)
+
+				calls := wget.Calls()
+				if !assert.Len(t, calls, 1) {
+					return
+				}
+
+				call := calls[0]
+				assert.True(t, call.HasArgs("--tries=5"), "args: %q", call.Args)
+				assert.True(t, call.HasArgs("-U", "shlib/dl/v1"), "args: %q", call.Args)
+				assert.True(t, call.HasArgs("--output-document", output, "https://example.com/asset.tar.gz"), "args: %q", call.Args)
+				assert.Equal(t, tc.auth != "", call.HasArgs("--header", tc.auth), "args: %q", call.Args)
+				// empty arguments would be treated as URLs by wget.
+				assert.NotContains(t, call.Args, "")
+			})
+		}
+	}
+}
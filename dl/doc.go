@@ -3,6 +3,7 @@
 // This go package exists to extensively unit test the shellscripts.
 // Test data can be generated with go generate
 
-//go:generate ./testdata/gen-hash-data.sh
+//go:generate go run ../internal/libtest/cmd/gen-checksum-fixtures -lib dl -target testdata/checksum.txt -out hash_data_test.go
+//go:generate go run ../internal/libtest/cmd/gen-exit-codes -lib dl -func shlib_explain_error -out exit-codes.json
 
 package dl
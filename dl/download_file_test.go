@@ -0,0 +1,414 @@
+//go:build linux
+// +build linux
+
+package dl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// downloadTools are the host tools used by shlib_download_file.
+var downloadTools = []string{"curl", "basename", "dirname", "mktemp", "mv", "rm", "cat", "tr", "grep", "sed", "uname", "sha256sum"}
+
+func Test_shlib_download_file(t *testing.T) {
+	// t.Parallel()
+	checksum, err := ioutil.ReadFile("testdata/checksum.txt")
+	if err != nil {
+		t.Fatalf("failed to read testdata: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		behaviors []libtest.AssetBehavior
+		args      []string
+		code      int
+		requests  int
+		auth      string
+		userAgent string
+	}{
+		{name: "success", requests: 1, userAgent: "shlib/dl/v1"},
+		{name: "auth-token", args: []string{"--auth-token", "ghp_foo"}, requests: 1, auth: "token ghp_foo", userAgent: "shlib/dl/v1"},
+		{name: "bearer-token", args: []string{"--bearer-token", "foo"}, requests: 1, auth: "Bearer foo", userAgent: "shlib/dl/v1"},
+		{name: "user-agent", args: []string{"--user-agent", "foo/v1"}, requests: 1, userAgent: "foo/v1"},
+		{name: "not-found", behaviors: []libtest.AssetBehavior{{Status: 404}}, code: 72, requests: 1, userAgent: "shlib/dl/v1"},
+		{name: "retry", behaviors: []libtest.AssetBehavior{{Status: 500}, {}}, requests: 2, userAgent: "shlib/dl/v1"},
+		{name: "slow", behaviors: []libtest.AssetBehavior{{Delay: 200 * time.Millisecond}}, requests: 1, userAgent: "shlib/dl/v1"},
+		{name: "truncated", behaviors: []libtest.AssetBehavior{{Truncate: true}}, code: 72, requests: 1, userAgent: "shlib/dl/v1"},
+		{name: "empty-with-checksum", behaviors: []libtest.AssetBehavior{{Empty: true}}, args: []string{"--checksum", "c7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177", "--checksum-algorithm", "sha256"}, code: 80, requests: 1, userAgent: "shlib/dl/v1"},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.code), func(t *testing.T) {
+				s := libtest.NewAssetServer(t)
+				s.Handle("/checksum.txt", tc.behaviors...)
+				sb := libtest.NewSandbox(t, downloadTools...)
+				output := filepath.Join(sb.Dir(), "checksum.txt")
+				audit := libtest.AuditFS(t, sb.Dir()).Allow(output)
+
+				args := append([]string{"--url", s.URL("/checksum.txt"), "--output", output}, tc.args...)
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+					libtest.WithSandbox(sb),
+					libtest.WithArgs(args...),
+					libtest.WithFSAudit(audit),
+				)
+
+				assert.Equal(t, tc.code, r.ExitCode, "stderr: %s", r.Stderr)
+				if tc.code == 0 {
+					data, err := ioutil.ReadFile(output)
+					assert.NoError(t, err)
+					assert.Equal(t, checksum, data)
+				}
+
+				requests := s.RequestsFor("/checksum.txt")
+				if assert.Len(t, requests, tc.requests) {
+					for _, req := range requests {
+						assert.Equal(t, tc.auth, req.Header.Get("Authorization"))
+						assert.Equal(t, tc.userAgent, req.Header.Get("User-Agent"))
+					}
+				}
+			})
+		}
+	}
+}
+
+func Test_shlib_download_file_cross_host_redirect(t *testing.T) {
+	// t.Parallel()
+	for _, shell := range libtest.Shells(t) {
+		t.Run(shell.Name, func(t *testing.T) {
+			s := libtest.NewAssetServer(t)
+			s.Handle("/redirect/checksum.txt", libtest.AssetBehavior{Location: s.AltURL("/checksum.txt")})
+			sb := libtest.NewSandbox(t, downloadTools...)
+
+			r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+				libtest.WithSandbox(sb),
+				libtest.WithArgs(
+					"--url", s.URL("/redirect/checksum.txt"),
+					"--output", filepath.Join(sb.Dir(), "checksum.txt"),
+					"--auth-token", "ghp_foo",
+				),
+			)
+			assert.Equal(t, 0, r.ExitCode, "stderr: %s", r.Stderr)
+
+			requests := s.Requests()
+			if assert.Len(t, requests, 2) {
+				assert.Equal(t, "token ghp_foo", requests[0].Header.Get("Authorization"))
+				// curl must not leak credentials to other hosts.
+				assert.Empty(t, requests[1].Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func Test_shlib_download_file_remote_checksum(t *testing.T) {
+	// t.Parallel()
+	tests := []struct {
+		name     string
+		checksum string
+		code     int
+	}{
+		{name: "valid", checksum: "/SHA256SUMS.txt"},
+		{name: "hash-only", checksum: "/SHA256SUMS.hash.txt"},
+		{name: "mismatch", checksum: "/SHA256SUMS.mismatch.txt", code: 80},
+		{name: "missing-entry", checksum: "/SHA256SUMS.missing.txt", code: 35},
+		{name: "not-found", checksum: "/SHA256SUMS.404.txt", code: 62},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.code), func(t *testing.T) {
+				s := libtest.NewAssetServer(t)
+				sb := libtest.NewSandbox(t, downloadTools...)
+
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+					libtest.WithSandbox(sb),
+					libtest.WithArgs(
+						"--url", s.URL("/checksum.txt"),
+						"--output", filepath.Join(sb.Dir(), "checksum.txt"),
+						"--checksum", s.URL(tc.checksum),
+						"--checksum-algorithm", "sha256",
+						"--auth-token", "ghp_foo",
+					),
+				)
+				assert.Equal(t, tc.code, r.ExitCode, "stderr: %s", r.Stderr)
+
+				if requests := s.RequestsFor(tc.checksum); assert.Len(t, requests, 1) {
+					assert.Equal(t, "token ghp_foo", requests[0].Header.Get("Authorization"))
+				}
+			})
+		}
+	}
+}
+
+func Test_shlib_download_file_gpg_key_id(t *testing.T) {
+	// t.Parallel()
+	f := gpgFixtures(t)
+
+	tests := []struct {
+		name      string
+		signature string
+		code      int
+	}{
+		{name: "valid", signature: f.Valid.Signature},
+		{name: "valid-armored", signature: f.Valid.ArmoredSignature},
+		{name: "mismatch", signature: f.Mismatch.Signature, code: 81},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.code), func(t *testing.T) {
+				home := libtest.GPGHome(t).Import(f.Valid.PublicKey)
+				s := libtest.NewAssetServer(t)
+				sb := libtest.NewSandbox(t, append(downloadTools, "gpg")...)
+
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+					libtest.WithSandbox(sb),
+					libtest.WithGPGHome(home),
+					libtest.WithArgs(
+						"--url", s.URL("/checksum.txt"),
+						"--output", filepath.Join(sb.Dir(), "checksum.txt"),
+						"--gpg-key", f.Valid.Fingerprint,
+						"--gpg-signature", tc.signature,
+					),
+				)
+				assert.Equal(t, tc.code, r.ExitCode, "stderr: %s", r.Stderr)
+
+				// key is found in the keyring, so it is never downloaded.
+				assert.Len(t, s.Requests(), 1)
+				home.AssertNotMutated(t)
+			})
+		}
+	}
+}
+
+func Test_shlib_download_file_gpg_key_symlink(t *testing.T) {
+	// t.Parallel()
+	f := gpgFixtures(t)
+	for _, shell := range libtest.Shells(t) {
+		t.Run(shell.Name, func(t *testing.T) {
+			// key is in the default keyring, so that verification would pass
+			// if the invalid key was ignored.
+			home := libtest.GPGHome(t).Import(f.Valid.PublicKey)
+			s := libtest.NewAssetServer(t)
+			sb := libtest.NewSandbox(t, append(downloadTools, "gpg")...)
+			key := filepath.Join(sb.Dir(), "key.gpg")
+			if err := os.Symlink(filepath.Join(sb.Dir(), "missing.gpg"), key); err != nil {
+				t.Fatalf("failed to create symlink: %s", err)
+			}
+
+			r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+				libtest.WithSandbox(sb),
+				libtest.WithGPGHome(home),
+				libtest.WithArgs(
+					"--url", s.URL("/checksum.txt"),
+					"--output", filepath.Join(sb.Dir(), "checksum.txt"),
+					"--gpg-key", key,
+					"--gpg-signature", f.Valid.Signature,
+				),
+			)
+			assert.Equal(t, 19, r.ExitCode, "stderr: %s", r.Stderr)
+			assert.Contains(t, r.Stderr, "GPG Key cannot be a symlink")
+			assert.Empty(t, s.Requests())
+		})
+	}
+}
+
+// fixtureRoute returns the route of the OpenPGP fixture file on an asset
+// server serving the fixtures directory.
+func fixtureRoute(t *testing.T, f libtest.OpenPGPFixtures, path string) string {
+	t.Helper()
+
+	rel, err := filepath.Rel(filepath.Dir(f.Target), path)
+	if err != nil {
+		t.Fatalf("fixture %s is not in fixtures directory: %s", path, err)
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+func Test_shlib_download_file_remote_gpg_signature(t *testing.T) {
+	// t.Parallel()
+	f := gpgFixtures(t)
+
+	tests := []struct {
+		name      string
+		signature string
+		behaviors []libtest.AssetBehavior
+		code      int
+	}{
+		{name: "valid", signature: f.Valid.Signature},
+		{name: "valid-armored", signature: f.Valid.ArmoredSignature},
+		{name: "mismatch", signature: f.Mismatch.Signature, code: 81},
+		{name: "not-found", signature: f.Valid.Signature, behaviors: []libtest.AssetBehavior{{Status: 404}}, code: 64},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.code), func(t *testing.T) {
+				home := libtest.GPGHome(t).Import(f.Valid.PublicKey)
+				s := libtest.NewAssetServerWithDir(t, filepath.Dir(f.Target))
+				signature := fixtureRoute(t, f, tc.signature)
+				s.Handle(signature, tc.behaviors...)
+				sb := libtest.NewSandbox(t, append(downloadTools, "gpg")...)
+
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+					libtest.WithSandbox(sb),
+					libtest.WithGPGHome(home),
+					libtest.WithArgs(
+						"--url", s.URL("/target.txt"),
+						"--output", filepath.Join(sb.Dir(), "target.txt"),
+						"--gpg-key", f.Valid.Fingerprint,
+						"--gpg-signature", s.URL(signature),
+						"--auth-token", "ghp_foo",
+					),
+				)
+				assert.Equal(t, tc.code, r.ExitCode, "stderr: %s", r.Stderr)
+
+				// signatures are downloaded like assets, with auth.
+				if requests := s.RequestsFor(signature); assert.Len(t, requests, 1) {
+					assert.Equal(t, "token ghp_foo", requests[0].Header.Get("Authorization"))
+				}
+				if tc.code == 64 {
+					assert.Empty(t, s.RequestsFor("/target.txt"))
+				}
+				home.AssertNotMutated(t)
+			})
+		}
+	}
+}
+
+func Test_shlib_download_file_remote_gpg_key(t *testing.T) {
+	// t.Parallel()
+	f := gpgFixtures(t)
+
+	tests := []struct {
+		name      string
+		signature string
+		behaviors []libtest.AssetBehavior
+		code      int
+	}{
+		{name: "valid", signature: f.Valid.Signature},
+		{name: "wrong-key", signature: f.WrongKey.Signature, code: 81},
+		{name: "not-found", signature: f.Valid.Signature, behaviors: []libtest.AssetBehavior{{Status: 404}}, code: 63},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.code), func(t *testing.T) {
+				// empty home, so that the key is only found in the downloaded
+				// keyring.
+				home := libtest.GPGHome(t)
+				s := libtest.NewAssetServerWithDir(t, filepath.Dir(f.Target))
+				key := fixtureRoute(t, f, f.Valid.PublicKey)
+				s.Handle(key, tc.behaviors...)
+				sb := libtest.NewSandbox(t, append(downloadTools, "gpg")...)
+
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+					libtest.WithSandbox(sb),
+					libtest.WithGPGHome(home),
+					libtest.WithArgs(
+						"--url", s.URL("/target.txt"),
+						"--output", filepath.Join(sb.Dir(), "target.txt"),
+						"--gpg-key", s.URL(key),
+						"--gpg-signature", tc.signature,
+						"--auth-token", "ghp_foo",
+					),
+				)
+				assert.Equal(t, tc.code, r.ExitCode, "stderr: %s", r.Stderr)
+
+				// tokens are never sent when fetching keys.
+				if requests := s.RequestsFor(key); assert.Len(t, requests, 1) {
+					assert.Empty(t, requests[0].Header.Get("Authorization"))
+				}
+				if tc.code == 63 {
+					assert.Empty(t, s.RequestsFor("/target.txt"))
+				}
+				// downloaded key is never imported.
+				home.AssertNotMutated(t)
+			})
+		}
+	}
+}
+
+func Test_shlib_download_file_existing_output(t *testing.T) {
+	// t.Parallel()
+	for _, shell := range libtest.Shells(t) {
+		t.Run(shell.Name, func(t *testing.T) {
+			s := libtest.NewAssetServer(t)
+			sb := libtest.NewSandbox(t, downloadTools...)
+			output := filepath.Join(sb.Dir(), "checksum.txt")
+			if err := ioutil.WriteFile(output, []byte("existing"), 0644); err != nil {
+				t.Fatalf("failed to create existing output: %s", err)
+			}
+
+			r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+				libtest.WithSandbox(sb),
+				libtest.WithArgs("--url", s.URL("/checksum.txt"), "--output", output),
+			)
+			assert.Equal(t, 103, r.ExitCode, "stderr: %s", r.Stderr)
+			assert.Contains(t, r.Stderr, output+" already exists")
+
+			data, err := ioutil.ReadFile(output)
+			assert.NoError(t, err)
+			assert.Equal(t, "existing", string(data))
+		})
+	}
+}
+
+func Test_shlib_download_file_existing_output_force(t *testing.T) {
+	// t.Parallel()
+	checksum, err := ioutil.ReadFile("testdata/checksum.txt")
+	if err != nil {
+		t.Fatalf("failed to read testdata: %s", err)
+	}
+
+	for _, shell := range libtest.Shells(t) {
+		t.Run(shell.Name, func(t *testing.T) {
+			s := libtest.NewAssetServer(t)
+			sb := libtest.NewSandbox(t, downloadTools...)
+			output := filepath.Join(sb.Dir(), "checksum.txt")
+			if err := ioutil.WriteFile(output, []byte("existing"), 0644); err != nil {
+				t.Fatalf("failed to create existing output: %s", err)
+			}
+			audit := libtest.AuditFS(t, sb.Dir()).Allow(output)
+
+			// --force is not the last argument, so that it must not consume
+			// the next one.
+			r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+				libtest.WithSandbox(sb),
+				libtest.WithArgs("--force", "--url", s.URL("/checksum.txt"), "--output", output),
+				libtest.WithFSAudit(audit),
+			)
+			assert.Equal(t, 0, r.ExitCode, "stderr: %s", r.Stderr)
+
+			data, err := ioutil.ReadFile(output)
+			assert.NoError(t, err)
+			assert.Equal(t, string(checksum), string(data))
+		})
+	}
+}
+
+func Test_shlib_download_file_unsupported_algorithm(t *testing.T) {
+	// t.Parallel()
+	for _, shell := range libtest.Shells(t) {
+		t.Run(shell.Name, func(t *testing.T) {
+			s := libtest.NewAssetServer(t)
+			sb := libtest.NewSandbox(t, downloadTools...)
+
+			r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_download_file "$@"`,
+				libtest.WithSandbox(sb),
+				libtest.WithArgs(
+					"--url", s.URL("/checksum.txt"),
+					"--output", filepath.Join(sb.Dir(), "checksum.txt"),
+					"--checksum", "c7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177",
+					"--checksum-algorithm", "sha3",
+				),
+			)
+			assert.Equal(t, 36, r.ExitCode, "stderr: %s", r.Stderr)
+			assert.Contains(t, r.Stderr, "Unsupported hash algorithm - sha3")
+			assert.Empty(t, s.Requests())
+		})
+	}
+}
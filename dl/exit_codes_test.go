@@ -0,0 +1,38 @@
+package dl
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// knownBadExitCodes are documented exit codes of dl, which are not returned
+// for the errors they explain. Returning them changes the exit codes seen by
+// users, thus they are fixed separately, along with a changelog entry.
+var knownBadExitCodes = []int{
+	// empty or GitHub bearer tokens return 5.
+	6,
+	// URLs are not validated, thus invalid URLs fail while downloading.
+	16,
+	// invalid checksums return 32.
+	17,
+	// missing sha512, sha1 and md5 hashers return 22.
+	23, 24, 25,
+	// destination which is not writable returns 31.
+	51,
+	// --force with a directory destination returns 3.
+	102,
+}
+
+func TestExitCodes(t *testing.T) {
+	catalog := libtest.CheckExitCodes(t, "dl", "shlib_explain_error", knownBadExitCodes...)
+
+	want, err := catalog.JSON()
+	require.NoError(t, err)
+	got, err := ioutil.ReadFile("exit-codes.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "exit-codes.json is out of date, run go generate")
+}
@@ -0,0 +1,114 @@
+//go:build go1.18
+// +build go1.18
+
+package dl
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// hasAnyPrefix returns true if s starts with any of the prefixes.
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// goarchOracle maps `uname -m` to GOARCH, empty input maps the host.
+func goarchOracle(arch string) (string, int) {
+	if arch == "" {
+		arch = libtest.UnameM()
+	}
+	switch {
+	case arch == "x86_64":
+		return "amd64", 0
+	case arch == "x86", arch == "i686", arch == "i386":
+		return "386", 0
+	case arch == "aarch64", arch == "arm64":
+		return "arm64", 0
+	case hasAnyPrefix(arch, "armv5", "armv6", "armv7", "armv8"):
+		return "arm", 0
+	}
+	return "", 11
+}
+
+// goarmOracle maps `uname -m` to GOARM, empty input maps the host.
+func goarmOracle(arch string) (string, int) {
+	if arch == "" {
+		arch = libtest.UnameM()
+	}
+	switch {
+	case arch == "x86", arch == "i686", arch == "i386", arch == "x86_64", arch == "aarch64", arch == "arm64":
+		return "", 0
+	case hasAnyPrefix(arch, "armv7", "armv8"):
+		return "7", 0
+	case strings.HasPrefix(arch, "armv6"):
+		return "6", 0
+	case strings.HasPrefix(arch, "armv5"):
+		return "5", 0
+	}
+	return "", 11
+}
+
+// goosOracle maps `uname -s` to GOOS, empty input maps the host.
+func goosOracle(os string) (string, int) {
+	if os == "" {
+		os = libtest.UnameS()
+	}
+	switch {
+	case os == "Linux":
+		return "linux", 0
+	case os == "Darwin":
+		return "darwin", 0
+	case hasAnyPrefix(os, "CYGWIN_NT", "Windows_NT", "MSYS_NT", "MINGW"):
+		return "windows", 0
+	case os == "FreeBSD":
+		return "freebsd", 0
+	}
+	return "", 1
+}
+
+var sha256HashRegex = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// isSHA256HashOracle returns 0 if the input is a lower case hex encoded
+// SHA256 hash.
+func isSHA256HashOracle(hash string) (string, int) {
+	if sha256HashRegex.MatchString(hash) {
+		return "", 0
+	}
+	return "", 1
+}
+
+func FuzzGOARCH(f *testing.F) {
+	libtest.FuzzFunction(f, []string{"dl"}, "__libdl_GOARCH", goarchOracle,
+		"", "x86_64", "i686", "aarch64", "arm64", "armv7l", "armv5tel", "armv", "x86_64 ", "ppc64le")
+}
+
+func FuzzGOARM(f *testing.F) {
+	libtest.FuzzFunction(f, []string{"dl"}, "__libdl_GOARM", goarmOracle,
+		"", "x86_64", "aarch64", "armv7l", "armv8b", "armv6", "armv5", "armv4", "FOO-BAR")
+}
+
+func FuzzGOOS(f *testing.F) {
+	libtest.FuzzFunction(f, []string{"dl"}, "__libdl_GOOS", goosOracle,
+		"", "Linux", "Darwin", "FreeBSD", "MINGW64_NT-10.0", "CYGWIN_NT-10.0", "linux", "Linux\n")
+}
+
+func FuzzIsSHA256Hash(f *testing.F) {
+	libtest.FuzzFunction(f, []string{"dl"}, "__libdl_is_sha256hash", isSHA256HashOracle,
+		"",
+		"c7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177",
+		"C7FF397DF263ECBF0AF7B717AFFA95C6A19FD784DBD20A210190CD5402556177",
+		"c7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd540255617",
+		"c7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177 ",
+		"foo\nc7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177",
+		"c7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177\n",
+	)
+}
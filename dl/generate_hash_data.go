@@ -1,3 +0,0 @@
-package dl
-
-//go:generate ./testdata/gen-hash-data.sh
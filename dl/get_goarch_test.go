@@ -1,9 +1,7 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"runtime"
 	"strings"
 	"testing"
@@ -14,7 +12,6 @@ import (
 
 func Test__libdl_GOARCH(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
 	tests := []struct {
 		arch   string
@@ -33,7 +30,7 @@ func Test__libdl_GOARCH(t *testing.T) {
 		{expect: runtime.GOARCH},
 		{arch: "FOO-BAR", code: 11},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
 			var tcArch string
 			if tc.arch == "" || strings.ToLower(tc.arch) == "default" {
@@ -41,23 +38,45 @@ func Test__libdl_GOARCH(t *testing.T) {
 			} else {
 				tcArch = tc.arch
 			}
-			t.Run(fmt.Sprintf("%s-%s", shell, tcArch), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && __libdl_GOARCH %s", tc.arch))
-				libtest.PrintCmdDebug(t, cmd)
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tcArch), func(t *testing.T) {
+				var args []string
+				if tc.arch != "" {
+					args = append(args, tc.arch)
+				}
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_GOARCH "$@"`, libtest.WithArgs(args...))
 
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				err := cmd.Run()
+				assert.Equal(t, tc.code, r.ExitCode)
+				assert.Equal(t, tc.expect, r.Stdout)
+				assert.Empty(t, r.Stderr)
+			})
+		}
+	}
+}
+
+func Test__libdl_GOARCH_uname(t *testing.T) {
+	// t.Parallel()
+	tests := []struct {
+		uname  string
+		expect string
+		code   int
+	}{
+		{uname: "x86_64\n", expect: "amd64"},
+		{uname: "aarch64\n", expect: "arm64"},
+		{uname: "armv7l\n", expect: "arm"},
+		{uname: "FOO-BAR\n", code: 11},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, strings.TrimSpace(tc.uname)), func(t *testing.T) {
+				uname := libtest.FakeCommand(t, "uname", libtest.FakeBehavior{Stdout: tc.uname})
 
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-				assert.Empty(t, stderrBuf.String())
-				assert.Equal(t, tc.expect, stdoutBuf.String())
+				r := libtest.Run(t, shell.Name, []string{"dl"}, "__libdl_GOARCH", libtest.WithSandbox(uname.Sandbox()))
 
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
+				assert.Equal(t, tc.code, r.ExitCode)
+				assert.Equal(t, tc.expect, r.Stdout)
+				assert.Empty(t, r.Stderr)
+				if calls := uname.Calls(); assert.Len(t, calls, 1) {
+					assert.Equal(t, []string{"-m"}, calls[0].Args)
 				}
 			})
 		}
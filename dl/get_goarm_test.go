@@ -1,9 +1,7 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +10,6 @@ import (
 
 func Test__libdl_GOARM(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
 	tests := []struct {
 		arch   string
@@ -31,25 +28,14 @@ func Test__libdl_GOARM(t *testing.T) {
 		{arch: "x86"},
 		{arch: "FOO-BAR", code: 11},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
-			t.Run(fmt.Sprintf("%s-%s", shell, tc.arch), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && __libdl_GOARM %s", tc.arch))
-				libtest.PrintCmdDebug(t, cmd)
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tc.arch), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_GOARM "$@"`, libtest.WithArgs(tc.arch))
 
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				err := cmd.Run()
-				assert.Equal(t, tc.expect, stdoutBuf.String())
-				assert.Empty(t, stderrBuf.String())
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
-				}
+				assert.Equal(t, tc.expect, r.Stdout)
+				assert.Empty(t, r.Stderr)
+				assert.Equal(t, tc.code, r.ExitCode)
 			})
 		}
 	}
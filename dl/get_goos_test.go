@@ -1,9 +1,7 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"runtime"
 	"strings"
 	"testing"
@@ -14,7 +12,6 @@ import (
 
 func Test__libdl_GOOS(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
 	tests := []struct {
 		os     string
@@ -29,7 +26,7 @@ func Test__libdl_GOOS(t *testing.T) {
 		{expect: runtime.GOOS, code: 0},
 		{os: "FOO-BAR", code: 1},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
 			var tcOS string
 			if tc.os == "" || strings.ToLower(tc.os) == "default" {
@@ -37,23 +34,16 @@ func Test__libdl_GOOS(t *testing.T) {
 			} else {
 				tcOS = tc.os
 			}
-			t.Run(fmt.Sprintf("%s-%s", shell, tcOS), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && __libdl_GOOS %s", tc.os))
-				libtest.PrintCmdDebug(t, cmd)
-
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				err := cmd.Run()
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-				assert.Equal(t, tc.expect, stdoutBuf.String())
-				assert.Empty(t, stderrBuf.String())
-
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tcOS), func(t *testing.T) {
+				var args []string
+				if tc.os != "" {
+					args = append(args, tc.os)
 				}
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_GOOS "$@"`, libtest.WithArgs(args...))
+
+				assert.Equal(t, tc.code, r.ExitCode)
+				assert.Equal(t, tc.expect, r.Stdout)
+				assert.Empty(t, r.Stderr)
 			})
 		}
 	}
@@ -1,13 +1,15 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os"
+	"io/ioutil"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tprasadtp/shlibs/internal/libtest"
 )
 
@@ -19,191 +21,126 @@ type gpgTestCase struct {
 	returnCode int
 }
 
-func Test__libdl_verify_gpg_valid(t *testing.T) {
-	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
-
-	tt := []gpgTestCase{
-		{
-			name:      "binary-detached",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.gpg",
-		},
-		{
-			name:      "ascii-detached",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.asc",
-		},
+// gpgFixtures generates OpenPGP keys and signatures of testdata/checksum.txt.
+func gpgFixtures(t *testing.T) libtest.OpenPGPFixtures {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skipf("gpg is not available on the host: %s", err)
 	}
 
-	for _, shell := range libtest.SupportedShells() {
-		for _, tc := range tt {
-			t.Run(fmt.Sprintf("%s-%s=%d", shell, tc.name, tc.returnCode), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_gpg_verify %s %s", tc.target, tc.signature))
-				libtest.PrintCmdDebug(t, cmd)
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "checksum.txt"))
+	require.NoError(t, err)
+	return libtest.NewOpenPGPFixtures(t, data)
+}
 
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC", "LOG_LVL=0")
-				err := cmd.Run()
+var gpgVersionRegex = regexp.MustCompile(`gpg \(GnuPG\) (\d+)\.(\d+)`)
 
-				assert.Nil(t, err)
-				assert.Empty(t, stdoutBuf.String())
-				assert.Contains(t, stderrBuf.String(), tc.signature)
-				assert.Contains(t, stderrBuf.String(), "VERIFIED")
-				assert.Equal(t, tc.returnCode, cmd.ProcessState.ExitCode())
-			})
-		}
-	}
+// gpgArmoredKeyringSupported returns true if installed gpg can use ASCII
+// armored files with --keyring. gpg 2.1 and above only read keybox or binary
+// keyrings and report missing public key otherwise.
+func gpgArmoredKeyringSupported(t *testing.T) bool {
+	t.Helper()
+
+	out, err := exec.Command("gpg", "--version").Output()
+	require.NoError(t, err)
+	m := gpgVersionRegex.FindSubmatch(out)
+	require.NotNil(t, m, "failed to parse gpg version: %s", out)
+	return string(m[1]) == "1"
 }
 
-func Test__libdl_verify_gpg_valid_custom_keyring(t *testing.T) {
+func Test__libdl_verify_gpg_default_keyring(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
+
+	f := gpgFixtures(t)
+	home := libtest.GPGHome(t).Import(
+		f.Valid.PublicKey,
+		f.Expired.PublicKey,
+		f.Revoked.PublicKey,
+		f.Subkey.PublicKey,
+		f.SHA1.PublicKey,
+	)
 
 	tt := []gpgTestCase{
-		{
-			name:      "binary-detached-binary-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.gpg",
-			keyring:   "testdata/GPG-PUBKEY.gpg",
-		},
-		{
-			name:      "ascii-detached-binary-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.asc",
-			keyring:   "testdata/GPG-PUBKEY.gpg",
-		},
-		{
-			name:      "binary-detached-ascii-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.gpg",
-			keyring:   "testdata/GPG-PUBKEY.asc",
-		},
-		{
-			name:      "ascii-detached-ascii-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.asc",
-			keyring:   "testdata/GPG-PUBKEY.asc",
-		},
+		{name: "binary-detached", target: f.Target, signature: f.Valid.Signature},
+		{name: "ascii-detached", target: f.Target, signature: f.Valid.ArmoredSignature},
+		{name: "signing-subkey", target: f.Target, signature: f.Subkey.Signature},
+		{name: "sha1-self-signatures", target: f.Target, signature: f.SHA1.Signature},
+		// gpg only warns about expired and revoked keys, as signatures were
+		// made before the key expired or was revoked.
+		{name: "expired-key", target: f.Target, signature: f.Expired.Signature},
+		{name: "revoked-key", target: f.Target, signature: f.Revoked.ArmoredSignature},
+		{name: "mismatch-binary-detached", target: f.Target, signature: f.Mismatch.Signature, returnCode: 81},
+		{name: "mismatch-ascii-detached", target: f.Target, signature: f.Mismatch.ArmoredSignature, returnCode: 81},
+		{name: "wrong-key", target: f.Target, signature: f.WrongKey.Signature, returnCode: 81},
 	}
 
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tt {
-			t.Run(fmt.Sprintf("%s-%s=%d", shell, tc.name, tc.returnCode), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_gpg_verify %s %s %s", tc.target, tc.signature, tc.keyring))
-				libtest.PrintCmdDebug(t, cmd)
-
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC", "LOG_LVL=0")
-				err := cmd.Run()
-
-				assert.Nil(t, err)
-				assert.Empty(t, stdoutBuf.String())
-				assert.Contains(t, stderrBuf.String(), tc.signature)
-				assert.Contains(t, stderrBuf.String(), tc.keyring)
-				assert.Contains(t, stderrBuf.String(), "VERIFIED")
-				assert.Equal(t, tc.returnCode, cmd.ProcessState.ExitCode())
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.returnCode), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_gpg_verify "$@"`,
+					libtest.WithArgs(tc.target, tc.signature),
+					libtest.WithEnv("TZ=UTC", "LOG_LVL=0"),
+					libtest.WithGPGHome(home),
+				)
+				assert.Empty(t, r.Stdout)
+				assert.Contains(t, r.Stderr, tc.signature)
+				if tc.returnCode == 0 {
+					assert.Contains(t, r.Stderr, "VERIFIED")
+				} else {
+					assert.Contains(t, r.Stderr, "FAILED")
+				}
+				assert.Equal(t, tc.returnCode, r.ExitCode)
+				home.AssertNotMutated(t)
 			})
 		}
 	}
 }
 
-// mismatch cases
-
-func Test__libdl_verify_gpg_mismatch(t *testing.T) {
+func Test__libdl_verify_gpg_custom_keyring(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
-
-	tt := []gpgTestCase{
-		{
-			name:      "binary-detached",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.mismatch.gpg",
-		},
-		{
-			name:      "ascii-detached",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.mismatch.asc",
-		},
-	}
 
-	for _, shell := range libtest.SupportedShells() {
-		for _, tc := range tt {
-			t.Run(fmt.Sprintf("%s-%s=%d", shell, tc.name, tc.returnCode), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_gpg_verify %s %s", tc.target, tc.signature))
-				libtest.PrintCmdDebug(t, cmd)
+	f := gpgFixtures(t)
+	// empty home, so that keys are only found in custom keyring.
+	home := libtest.GPGHome(t)
 
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC", "LOG_LVL=0")
-				err := cmd.Run()
-				tc.returnCode = 81
-
-				assert.NotNil(t, err)
-				assert.Empty(t, stdoutBuf.String())
-				assert.Contains(t, stderrBuf.String(), tc.signature)
-				assert.Contains(t, stderrBuf.String(), "FAILED")
-				assert.Equal(t, tc.returnCode, cmd.ProcessState.ExitCode())
-			})
-		}
+	armoredReturnCode := 81
+	if gpgArmoredKeyringSupported(t) {
+		armoredReturnCode = 0
 	}
-}
-
-func Test__libdl_verify_gpg_mismatch_custom_keyring(t *testing.T) {
-	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
 	tt := []gpgTestCase{
-		{
-			name:      "binary-detached-binary-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.mismatch.gpg",
-			keyring:   "testdata/GPG-PUBKEY.gpg",
-		},
-		{
-			name:      "ascii-detached-binary-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.mismatch.asc",
-			keyring:   "testdata/GPG-PUBKEY.gpg",
-		},
-		{
-			name:      "binary-detached-ascii-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.mismatch.gpg",
-			keyring:   "testdata/GPG-PUBKEY.asc",
-		},
-		{
-			name:      "ascii-detached-ascii-keyring",
-			target:    "testdata/checksum.txt",
-			signature: "testdata/checksum.txt.mismatch.asc",
-			keyring:   "testdata/GPG-PUBKEY.asc",
-		},
+		{name: "binary-detached-binary-keyring", target: f.Target, signature: f.Valid.Signature, keyring: f.Valid.PublicKey},
+		{name: "ascii-detached-binary-keyring", target: f.Target, signature: f.Valid.ArmoredSignature, keyring: f.Valid.PublicKey},
+		{name: "binary-detached-ascii-keyring", target: f.Target, signature: f.Valid.Signature, keyring: f.Valid.ArmoredPublicKey, returnCode: armoredReturnCode},
+		{name: "ascii-detached-ascii-keyring", target: f.Target, signature: f.Valid.ArmoredSignature, keyring: f.Valid.ArmoredPublicKey, returnCode: armoredReturnCode},
+		{name: "signing-subkey", target: f.Target, signature: f.Subkey.Signature, keyring: f.Subkey.PublicKey},
+		{name: "sha1-self-signatures", target: f.Target, signature: f.SHA1.Signature, keyring: f.SHA1.PublicKey},
+		{name: "expired-key", target: f.Target, signature: f.Expired.Signature, keyring: f.Expired.PublicKey},
+		{name: "revoked-key", target: f.Target, signature: f.Revoked.Signature, keyring: f.Revoked.PublicKey},
+		{name: "mismatch-binary-detached", target: f.Target, signature: f.Mismatch.Signature, keyring: f.Mismatch.PublicKey, returnCode: 81},
+		{name: "mismatch-ascii-detached", target: f.Target, signature: f.Mismatch.ArmoredSignature, keyring: f.Mismatch.PublicKey, returnCode: 81},
+		{name: "wrong-key", target: f.Target, signature: f.WrongKey.Signature, keyring: f.WrongKey.PublicKey, returnCode: 81},
+		{name: "key-not-in-keyring", target: f.Target, signature: f.Valid.Signature, keyring: f.Subkey.PublicKey, returnCode: 81},
 	}
 
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tt {
-			t.Run(fmt.Sprintf("%s-%s=%d", shell, tc.name, tc.returnCode), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_gpg_verify %s %s %s", tc.target, tc.signature, tc.keyring))
-				libtest.PrintCmdDebug(t, cmd)
-
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC", "LOG_LVL=0")
-				err := cmd.Run()
-				tc.returnCode = 81
-
-				assert.NotNil(t, err)
-				assert.Empty(t, stdoutBuf.String())
-				assert.Contains(t, stderrBuf.String(), tc.signature)
-				assert.Contains(t, stderrBuf.String(), tc.keyring)
-				assert.Contains(t, stderrBuf.String(), "FAILED")
-				assert.Equal(t, tc.returnCode, cmd.ProcessState.ExitCode())
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.returnCode), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_gpg_verify "$@"`,
+					libtest.WithArgs(tc.target, tc.signature, tc.keyring),
+					libtest.WithEnv("TZ=UTC", "LOG_LVL=0"),
+					libtest.WithGPGHome(home),
+				)
+				assert.Empty(t, r.Stdout)
+				assert.Contains(t, r.Stderr, tc.signature)
+				assert.Contains(t, r.Stderr, tc.keyring)
+				if tc.returnCode == 0 {
+					assert.Contains(t, r.Stderr, "VERIFIED")
+				} else {
+					assert.Contains(t, r.Stderr, "FAILED")
+				}
+				assert.Equal(t, tc.returnCode, r.ExitCode)
+				home.AssertNotMutated(t)
 			})
 		}
 	}
@@ -211,61 +148,59 @@ func Test__libdl_verify_gpg_mismatch_custom_keyring(t *testing.T) {
 
 func Test__libdl_verify_gpg_missing_files(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
+
+	f := gpgFixtures(t)
+	home := libtest.GPGHome(t)
 
 	tt := []gpgTestCase{
 		{
 			name:       "nonexistant-target",
 			target:     "testdata/missing.txt",
-			signature:  "testdata/checksum.txt.gpg",
-			keyring:    "testdata/GPG-PUBKEY.gpg",
+			signature:  f.Valid.Signature,
+			keyring:    f.Valid.PublicKey,
 			returnCode: 41,
 		},
 		{
 			name:       "nonexistant-signature",
-			target:     "testdata/checksum.txt",
+			target:     f.Target,
 			signature:  "testdata/missing.txt.gpg",
-			keyring:    "testdata/GPG-PUBKEY.gpg",
+			keyring:    f.Valid.PublicKey,
 			returnCode: 42,
 		},
 		{
 			name:       "nonexistant-keyring",
-			target:     "testdata/checksum.txt",
-			signature:  "testdata/checksum.txt.gpg",
+			target:     f.Target,
+			signature:  f.Valid.Signature,
 			keyring:    "testdata/NO-SUCH-KEYRING.asc",
 			returnCode: 43,
 		},
 		{
 			name:       "empty-target",
-			target:     `""`,
-			signature:  "testdata/checksum.txt.gpg",
-			keyring:    "testdata/GPG-PUBKEY.asc",
+			target:     "",
+			signature:  f.Valid.Signature,
+			keyring:    f.Valid.ArmoredPublicKey,
 			returnCode: 12,
 		},
 		{
 			name:       "empty-signature",
-			target:     "testdata/checksum.txt",
-			signature:  `""`,
-			keyring:    "testdata/GPG-PUBKEY.asc",
+			target:     f.Target,
+			signature:  "",
+			keyring:    f.Valid.ArmoredPublicKey,
 			returnCode: 12,
 		},
 	}
 
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tt {
-			t.Run(fmt.Sprintf("%s-%s=%d", shell, tc.name, tc.returnCode), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_gpg_verify %s %s %s", tc.target, tc.signature, tc.keyring))
-				libtest.PrintCmdDebug(t, cmd)
-
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC", "LOG_LVL=0")
-				err := cmd.Run()
-
-				assert.NotNil(t, err)
-				assert.Empty(t, stdoutBuf.String())
-				assert.Equal(t, tc.returnCode, cmd.ProcessState.ExitCode())
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.name, tc.returnCode), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_gpg_verify "$@"`,
+					libtest.WithArgs(tc.target, tc.signature, tc.keyring),
+					libtest.WithEnv("TZ=UTC", "LOG_LVL=0"),
+					libtest.WithGPGHome(home),
+				)
+				assert.Empty(t, r.Stdout)
+				assert.Equal(t, tc.returnCode, r.ExitCode)
+				home.AssertNotMutated(t)
 			})
 		}
 	}
@@ -1,9 +1,7 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,38 +10,25 @@ import (
 
 func Test_libdl_has_command(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
 	tests := []struct {
-		name    string
-		command string
-		code    int
+		name string
+		args []string
+		code int
 	}{
-		{name: "ls", command: "ls", code: 0},
-		{name: "non-existing-command", command: "non-existing-command", code: 1},
-		{name: "empty-quote", command: `""`, code: 1},
+		{name: "ls", args: []string{"ls"}, code: 0},
+		{name: "non-existing-command", args: []string{"non-existing-command"}, code: 1},
+		{name: "empty-quote", args: []string{""}, code: 1},
 		{name: "empty", code: 1},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
-			t.Run(fmt.Sprintf("%s-%s", shell, tc.command), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && __libdl_has_command %s", tc.command))
-				libtest.PrintCmdDebug(t, cmd)
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tc.name), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_has_command "$@"`, libtest.WithArgs(tc.args...))
 
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				err := cmd.Run()
-
-				assert.Empty(t, stdoutBuf.String())
-				assert.Empty(t, stderrBuf.String())
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
-				}
+				assert.Empty(t, r.Stdout)
+				assert.Empty(t, r.Stderr)
+				assert.Equal(t, tc.code, r.ExitCode)
 			})
 		}
 	}
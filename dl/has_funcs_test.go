@@ -4,10 +4,8 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
 	"math/rand"
-	"os/exec"
 	"testing"
 	"time"
 
@@ -17,56 +15,33 @@ import (
 
 func Test__libdl_has_depfuncs_success(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
-	for _, shell := range libtest.SupportedShells() {
-		t.Run(shell, func(t *testing.T) {
-			cmd := exec.Command(shell,
-				"-c", ". ./../logger/logger.sh && . ./dl.sh && __libdl_has_depfuncs")
-			libtest.PrintCmdDebug(t, cmd)
+	for _, shell := range libtest.Shells(t) {
+		t.Run(shell.Name, func(t *testing.T) {
+			r := libtest.Run(t, shell.Name, []string{"dl"}, "__libdl_has_depfuncs")
 
-			var stdoutBuf, stderrBuf bytes.Buffer
-			cmd.Stdout = &stdoutBuf
-			cmd.Stderr = &stderrBuf
-			err := cmd.Run()
-			assert.Nil(t, err)
-			assert.Equal(t, 0, cmd.ProcessState.ExitCode())
-			assert.Empty(t, stderrBuf.String())
-			assert.Empty(t, stdoutBuf.String())
+			assert.Equal(t, 0, r.ExitCode)
+			assert.Empty(t, r.Stderr)
+			assert.Empty(t, r.Stdout)
 		})
 	}
 }
 
 func Test__libdl_has_depfuncs_missing(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 	logFuncs := []string{"log_trace", "log_debug", "log_info", "log_success", "log_warning", "log_notice", "log_error"}
 	rand.Seed(time.Now().Unix())
 
-	tests := []struct {
-		shell    string
-		undefine string
-	}{
-		{shell: "bash", undefine: logFuncs[rand.Intn(len(logFuncs))]},
-		{shell: "zsh", undefine: logFuncs[rand.Intn(len(logFuncs))]},
-		{shell: "sh", undefine: logFuncs[rand.Intn(len(logFuncs))]},
-		{shell: "dash", undefine: logFuncs[rand.Intn(len(logFuncs))]},
-	}
-	for _, tc := range tests {
-		t.Run(fmt.Sprintf("%s-missing-%s", tc.shell, tc.undefine), func(t *testing.T) {
-			cmd := exec.Command(tc.shell,
-				"-c", fmt.Sprintf(". ./../logger/logger.sh && . ./dl.sh && unset -f %s && __libdl_has_depfuncs", tc.undefine))
-
-			libtest.PrintCmdDebug(t, cmd)
+	for _, shell := range libtest.Shells(t) {
+		undefine := logFuncs[rand.Intn(len(logFuncs))]
+		t.Run(fmt.Sprintf("%s-missing-%s", shell.Name, undefine), func(t *testing.T) {
+			r := libtest.Run(t, shell.Name, []string{"dl"}, `unset -f "$1" && __libdl_has_depfuncs`,
+				libtest.WithArgs(undefine),
+			)
 
-			var stdoutBuf, stderrBuf bytes.Buffer
-			cmd.Stdout = &stdoutBuf
-			cmd.Stderr = &stderrBuf
-			err := cmd.Run()
-			assert.NotNil(t, err)
-			assert.Equal(t, 1, cmd.ProcessState.ExitCode())
-			assert.Empty(t, stderrBuf.String())
-			assert.Empty(t, stdoutBuf.String())
+			assert.Equal(t, 1, r.ExitCode)
+			assert.Empty(t, r.Stderr)
+			assert.Empty(t, r.Stdout)
 		})
 	}
 }
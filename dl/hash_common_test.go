@@ -5,6 +5,21 @@ type hashTestTable struct {
 	shell          string
 	hasherOverride string
 	targetFile     string
+	omitTarget     bool
 	returnCode     int
 	expectedHash   string
 }
+
+// args returns the positional arguments for the hash function. Target file
+// is omitted if omitTarget is set and hasher override is omitted if it is
+// set to "none".
+func (tc hashTestTable) args() []string {
+	var args []string
+	if !tc.omitTarget {
+		args = append(args, tc.targetFile)
+	}
+	if tc.hasherOverride != "none" {
+		args = append(args, tc.hasherOverride)
+	}
+	return args
+}
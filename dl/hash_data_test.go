@@ -0,0 +1,101 @@
+// Code generated by gen-checksum-fixtures. DO NOT EDIT.
+
+package dl
+
+import "github.com/tprasadtp/shlibs/internal/libtest"
+
+// checksumFixtures are checksum fixtures by hash algorithm.
+var checksumFixtures = map[string]libtest.ChecksumFixture{
+	"md5": {
+		Algorithm: "md5",
+		Valid:     "f25eb2f56cad9ff59dff0e9dd2b64251",
+		Mismatch:  "6890e4b9b510fddbc8dbc3a35f1f3821",
+		Invalid:   "25eb2f56cad9ff59dff0e9dd2b64251",
+		Files: libtest.ChecksumFiles{
+			Valid:        "testdata/MD5SUMS.txt",
+			Invalid:      "testdata/MD5SUMS.invalid.txt",
+			Missing:      "testdata/MD5SUMS.missing.txt",
+			Mismatch:     "testdata/MD5SUMS.mismatch.txt",
+			CRLF:         "testdata/MD5SUMS.crlf.txt",
+			BSD:          "testdata/MD5SUMS.bsd.txt",
+			Multi:        "testdata/MD5SUMS.multi.txt",
+			MultiPrefix:  "testdata/MD5SUMS.multi-prefix.txt",
+			Hash:         "testdata/MD5SUMS.hash.txt",
+			HashLF:       "testdata/MD5SUMS.hash.lf-1.txt",
+			HashLFLF:     "testdata/MD5SUMS.hash.lf-2.txt",
+			HashCR:       "testdata/MD5SUMS.hash.cr-1.txt",
+			HashCRLF:     "testdata/MD5SUMS.hash.crlf-1.txt",
+			HashInvalid:  "testdata/MD5SUMS.hash.invalid.txt",
+			HashMismatch: "testdata/MD5SUMS.hash.mismatch.txt",
+		},
+	},
+	"sha1": {
+		Algorithm: "sha1",
+		Valid:     "b5db34c1b59b6e0c223b103ba52967dbb59c2f8b",
+		Mismatch:  "2272b766331da5c81006e50add45d0bb9754c697",
+		Invalid:   "5db34c1b59b6e0c223b103ba52967dbb59c2f8b",
+		Files: libtest.ChecksumFiles{
+			Valid:        "testdata/SHA1SUMS.txt",
+			Invalid:      "testdata/SHA1SUMS.invalid.txt",
+			Missing:      "testdata/SHA1SUMS.missing.txt",
+			Mismatch:     "testdata/SHA1SUMS.mismatch.txt",
+			CRLF:         "testdata/SHA1SUMS.crlf.txt",
+			BSD:          "testdata/SHA1SUMS.bsd.txt",
+			Multi:        "testdata/SHA1SUMS.multi.txt",
+			MultiPrefix:  "testdata/SHA1SUMS.multi-prefix.txt",
+			Hash:         "testdata/SHA1SUMS.hash.txt",
+			HashLF:       "testdata/SHA1SUMS.hash.lf-1.txt",
+			HashLFLF:     "testdata/SHA1SUMS.hash.lf-2.txt",
+			HashCR:       "testdata/SHA1SUMS.hash.cr-1.txt",
+			HashCRLF:     "testdata/SHA1SUMS.hash.crlf-1.txt",
+			HashInvalid:  "testdata/SHA1SUMS.hash.invalid.txt",
+			HashMismatch: "testdata/SHA1SUMS.hash.mismatch.txt",
+		},
+	},
+	"sha256": {
+		Algorithm: "sha256",
+		Valid:     "c7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177",
+		Mismatch:  "8b07d64bcf0f13af97eb188d2b36a6c2980680e360b4a2fe470633f465511b25",
+		Invalid:   "7ff397df263ecbf0af7b717affa95c6a19fd784dbd20a210190cd5402556177",
+		Files: libtest.ChecksumFiles{
+			Valid:        "testdata/SHA256SUMS.txt",
+			Invalid:      "testdata/SHA256SUMS.invalid.txt",
+			Missing:      "testdata/SHA256SUMS.missing.txt",
+			Mismatch:     "testdata/SHA256SUMS.mismatch.txt",
+			CRLF:         "testdata/SHA256SUMS.crlf.txt",
+			BSD:          "testdata/SHA256SUMS.bsd.txt",
+			Multi:        "testdata/SHA256SUMS.multi.txt",
+			MultiPrefix:  "testdata/SHA256SUMS.multi-prefix.txt",
+			Hash:         "testdata/SHA256SUMS.hash.txt",
+			HashLF:       "testdata/SHA256SUMS.hash.lf-1.txt",
+			HashLFLF:     "testdata/SHA256SUMS.hash.lf-2.txt",
+			HashCR:       "testdata/SHA256SUMS.hash.cr-1.txt",
+			HashCRLF:     "testdata/SHA256SUMS.hash.crlf-1.txt",
+			HashInvalid:  "testdata/SHA256SUMS.hash.invalid.txt",
+			HashMismatch: "testdata/SHA256SUMS.hash.mismatch.txt",
+		},
+	},
+	"sha512": {
+		Algorithm: "sha512",
+		Valid:     "948da6c339b8a2edd280ac2a6b1bcf9b181338b8ce92542f6cfad47f63684cf9d58aa72079bcd4f8c6db4ab83643fcd6c4a11e60ccca2ccfec643875528c4e64",
+		Mismatch:  "387d2e53cd89f817429cdd651d7503b2d6f269b44f6caf5206c409fcf1c26738e6d316e5a2deb5e0a30d2f6795963e3a3e74eb5a96311e1bb5bca0f16b7bdb4e",
+		Invalid:   "48da6c339b8a2edd280ac2a6b1bcf9b181338b8ce92542f6cfad47f63684cf9d58aa72079bcd4f8c6db4ab83643fcd6c4a11e60ccca2ccfec643875528c4e64",
+		Files: libtest.ChecksumFiles{
+			Valid:        "testdata/SHA512SUMS.txt",
+			Invalid:      "testdata/SHA512SUMS.invalid.txt",
+			Missing:      "testdata/SHA512SUMS.missing.txt",
+			Mismatch:     "testdata/SHA512SUMS.mismatch.txt",
+			CRLF:         "testdata/SHA512SUMS.crlf.txt",
+			BSD:          "testdata/SHA512SUMS.bsd.txt",
+			Multi:        "testdata/SHA512SUMS.multi.txt",
+			MultiPrefix:  "testdata/SHA512SUMS.multi-prefix.txt",
+			Hash:         "testdata/SHA512SUMS.hash.txt",
+			HashLF:       "testdata/SHA512SUMS.hash.lf-1.txt",
+			HashLFLF:     "testdata/SHA512SUMS.hash.lf-2.txt",
+			HashCR:       "testdata/SHA512SUMS.hash.cr-1.txt",
+			HashCRLF:     "testdata/SHA512SUMS.hash.crlf-1.txt",
+			HashInvalid:  "testdata/SHA512SUMS.hash.invalid.txt",
+			HashMismatch: "testdata/SHA512SUMS.hash.mismatch.txt",
+		},
+	},
+}
@@ -1,38 +1,35 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os"
-	"os/exec"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tprasadtp/shlibs/internal/libtest"
 )
 
-func generatesha1TestTable() []hashTestTable {
+func generatesha1TestTable(t *testing.T) []hashTestTable {
 	var testCases []hashTestTable
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, hasherOverride := range []string{"auto", "sha1sum", "shasum", "none"} {
 			for _, variant := range []string{"existing-file", "non-existant-file", "empty-quotes", "empty"} {
 				var tc hashTestTable
-				name := fmt.Sprintf("%s-hasher-override-%s-%s", shell, hasherOverride, variant)
+				name := fmt.Sprintf("%s-hasher-override-%s-%s", shell.Name, hasherOverride, variant)
 
 				switch variant {
 				case "existing-file":
 					tc = hashTestTable{
 						name:           name,
-						shell:          shell,
+						shell:          shell.Name,
 						hasherOverride: hasherOverride,
 						targetFile:     "testdata/checksum.txt",
-						expectedHash:   SHA1_VALID,
+						expectedHash:   checksumFixtures["sha1"].Valid,
 						returnCode:     0,
 					}
 				case "non-existant-file":
 					tc = hashTestTable{
 						name:           name,
-						shell:          shell,
+						shell:          shell.Name,
 						hasherOverride: hasherOverride,
 						targetFile:     "testdata/non-existant-file.txt",
 						returnCode:     31,
@@ -40,9 +37,9 @@ func generatesha1TestTable() []hashTestTable {
 				case "empty-quotes":
 					tc = hashTestTable{
 						name:           name,
-						shell:          shell,
+						shell:          shell.Name,
 						hasherOverride: hasherOverride,
-						targetFile:     `""`,
+						targetFile:     "",
 						returnCode:     12,
 					}
 				case "empty":
@@ -55,8 +52,9 @@ func generatesha1TestTable() []hashTestTable {
 					}
 					tc = hashTestTable{
 						name:           name,
-						shell:          shell,
+						shell:          shell.Name,
 						hasherOverride: hasherOverride,
+						omitTarget:     true,
 						returnCode:     rc,
 					}
 				}
@@ -71,33 +69,21 @@ func generatesha1TestTable() []hashTestTable {
 
 func Test__libdl_hash_sha1(t *testing.T) {
 	// t.Parallel()
-	testCases := generatesha1TestTable()
+	testCases := generatesha1TestTable(t)
 	t.Logf("SHA1 Total test cases: %d", len(testCases))
 	for _, tc := range testCases {
 		t.Run(fmt.Sprintf("%s=%d", tc.name, tc.returnCode), func(t *testing.T) {
-			var cmd *exec.Cmd
-			if tc.hasherOverride == "none" {
-				cmd = exec.Command(tc.shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_hash_sha1 %s", tc.targetFile))
-			} else {
-				cmd = exec.Command(tc.shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_hash_sha1 %s %s", tc.targetFile, tc.hasherOverride))
-			}
-
-			libtest.PrintCmdDebug(t, cmd)
-			var stdoutBuf, stderrBuf bytes.Buffer
-			cmd.Stdout = &stdoutBuf
-			cmd.Stderr = &stderrBuf
-			cmd.Env = append(os.Environ(), "TZ=UTC")
-
-			err := cmd.Run()
-			assert.Equal(t, tc.returnCode, cmd.ProcessState.ExitCode())
+			r := libtest.Run(t, tc.shell, []string{"dl"}, `__libdl_hash_sha1 "$@"`,
+				libtest.WithArgs(tc.args()...),
+				libtest.WithEnv("TZ=UTC"),
+			)
+			assert.Equal(t, tc.returnCode, r.ExitCode)
 
 			if tc.returnCode == 0 {
-				assert.Nil(t, err)
-				assert.Empty(t, stderrBuf.String())
-				assert.Equal(t, tc.expectedHash, stdoutBuf.String())
+				assert.Empty(t, r.Stderr)
+				assert.Equal(t, tc.expectedHash, r.Stdout)
 			} else {
-				assert.NotNil(t, err)
-				assert.Empty(t, stdoutBuf.String())
+				assert.Empty(t, r.Stdout)
 			}
 		})
 	}
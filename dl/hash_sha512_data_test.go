@@ -1,8 +0,0 @@
-// Code generated by testdata/gendata.sh DO NOT EDIT.
-package dl
-
-//Generated Hashes
-const SHA512_VALID = "948da6c339b8a2edd280ac2a6b1bcf9b181338b8ce92542f6cfad47f63684cf9d58aa72079bcd4f8c6db4ab83643fcd6c4a11e60ccca2ccfec643875528c4e64"
-const SHA512_MISMATCH = "f3c01a90097ad064abd368fb516d583ac21698c11c19d42b214af56123751d3faf94eba96ec6cdcbf41ba6c9833ef0ff56cdb258278f78e9d3b61078b0bce251"
-const SHA512_INVALID = "48da6c339b8a2edd280ac2a6b1bcf9b181338b8ce92542f6cfad47f63684cf9d58aa72079bcd4f8c6db4ab83643fcd6c4a11e60ccca2ccfec643875528c4e64"
-
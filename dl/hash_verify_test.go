@@ -0,0 +1,191 @@
+package dl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// hashTypeInputs returns all the spellings of the algorithm accepted by
+// __libdl_hash_verify, like sha256, sha-256, SHA256 and SHA-256.
+func hashTypeInputs(algorithm string) []string {
+	dashed := algorithm
+	if i := strings.IndexAny(algorithm, "0123456789"); i > 0 {
+		dashed = algorithm[:i] + "-" + algorithm[i:]
+	}
+	return []string{algorithm, dashed, strings.ToUpper(algorithm), strings.ToUpper(dashed)}
+}
+
+func Test__libdl_hash_verify(t *testing.T) {
+	// t.Parallel()
+
+	var algorithms []string
+	for algorithm := range checksumFixtures {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+
+	type testCase struct {
+		name      string
+		file      string
+		code      int
+		hash      string
+		errString string
+	}
+
+	for _, algorithm := range algorithms {
+		f := checksumFixtures[algorithm]
+		notFound := fmt.Sprintf("failed to find %s hash corresponding to", algorithm)
+
+		tests := []testCase{
+			{
+				name: "existing-file-raw-hash-match",
+				file: "testdata/checksum.txt",
+				hash: f.Valid,
+			},
+			{
+				name: "existing-file-checksum-file-match",
+				file: "testdata/checksum.txt",
+				hash: f.Files.Valid,
+			},
+			{
+				name: "existing-file-checksum-file-crlf-match",
+				file: "testdata/checksum.txt",
+				hash: f.Files.CRLF,
+			},
+			{
+				name: "existing-file-checksum-file-multi-entry-match",
+				file: "testdata/checksum.txt",
+				hash: f.Files.Multi,
+			},
+			// Checksums failure
+			{
+				name: "existing-file-raw-hash-err-on-mismatch",
+				file: "testdata/checksum.txt",
+				hash: f.Mismatch,
+				code: 80,
+			},
+			{
+				name: "existing-file-checksum-err-on-mismatch",
+				file: "testdata/checksum.txt",
+				hash: f.Files.Mismatch,
+				code: 80,
+			},
+			// First entry whose name contains the target name is used, so
+			// entries like checksum.txt.sig before checksum.txt cause a mismatch.
+			{
+				name: "existing-file-checksum-file-multi-entry-prefix-err-on-mismatch",
+				file: "testdata/checksum.txt",
+				hash: f.Files.MultiPrefix,
+				code: 80,
+			},
+			// Target is missing
+			{
+				name: "non-existing-target-err-checksum-raw",
+				file: "testdata/no-such-file.txt",
+				hash: f.Valid,
+				code: 31,
+			},
+			{
+				name: "non-existing-target-err-checksum-file",
+				file: "testdata/no-such-file.txt",
+				hash: f.Files.Valid,
+				code: 31,
+			},
+			// Invalid checksum
+			{
+				name: "existing-file-raw-hash-invalid-looks-for-file",
+				file: "testdata/checksum.txt",
+				hash: f.Invalid,
+				code: 32,
+			},
+			{
+				name: "existing-file-checksum-file-invalid-checksum",
+				file: "testdata/checksum.txt",
+				hash: f.Files.Invalid,
+				code: 35,
+			},
+			// BSD style checksum files are not supported.
+			{
+				name:      "existing-file-checksum-file-bsd-style",
+				file:      "testdata/checksum.txt",
+				hash:      f.Files.BSD,
+				errString: notFound,
+				code:      35,
+			},
+			// Target missing from checksums file
+			{
+				name:      "existing-file-err-on-missing-from-hashes-file",
+				file:      "testdata/checksum.txt",
+				hash:      f.Files.Missing,
+				errString: notFound,
+				code:      35,
+			},
+			// File contining just checksums, as it fallbacks to checksum file,
+			// errors return 35
+			{
+				name: "checksum-file-has-only-hash-value",
+				file: "testdata/checksum.txt",
+				hash: f.Files.Hash,
+			},
+			{
+				name: "checksum-file-has-only-hash-value-with-newline",
+				file: "testdata/checksum.txt",
+				hash: f.Files.HashLF,
+			},
+			{
+				name: "checksum-file-has-only-hash-value-with-newlines",
+				file: "testdata/checksum.txt",
+				hash: f.Files.HashLFLF,
+			},
+			{
+				name: "checksum-file-has-only-hash-value-with-cr",
+				file: "testdata/checksum.txt",
+				hash: f.Files.HashCR,
+			},
+			{
+				name: "checksum-file-has-only-hash-value-with-crlf",
+				file: "testdata/checksum.txt",
+				hash: f.Files.HashCRLF,
+			},
+			// mismatch should return checksum error
+			// as the has in the file is a valid hash, but fails to match
+			{
+				name: "checksum-file-has-only-hash-mismatch",
+				file: "testdata/checksum.txt",
+				hash: f.Files.HashMismatch,
+				code: 80,
+			},
+			// these will cause the code to treat them as standard files and fail
+			{
+				name:      "checksum-file-has-only-hash-value-but-invalid",
+				file:      "testdata/checksum.txt",
+				errString: notFound,
+				hash:      f.Files.HashInvalid,
+				code:      35,
+			},
+		}
+
+		for _, shell := range libtest.Shells(t) {
+			for _, tc := range tests {
+				for _, hashTypeInput := range hashTypeInputs(algorithm) {
+					t.Run(fmt.Sprintf("%s-%s-%s=%d", shell.Name, tc.name, hashTypeInput, tc.code), func(t *testing.T) {
+						r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_hash_verify "$@"`,
+							libtest.WithArgs(tc.file, tc.hash, hashTypeInput),
+							libtest.WithEnv("TZ=UTC", "LOG_LVL=0"),
+						)
+						assert.Empty(t, r.Stdout)
+						if tc.code != 0 {
+							assert.Contains(t, strings.ToLower(r.Stderr), tc.errString)
+						}
+						assert.Equal(t, tc.code, r.ExitCode)
+					})
+				}
+			}
+		}
+	}
+}
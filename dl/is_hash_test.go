@@ -1,10 +1,7 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os"
-	"os/exec"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,41 +10,28 @@ import (
 
 func Test__libdl_is_md5hash(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
 	tests := []struct {
 		name string
 		code int
-		hash string
+		args []string
 	}{
-		{name: "valid", hash: MD5_VALID},
-		{name: "invalid", hash: MD5_INVALID, code: 1},
-		{name: "filename", hash: "testdata/MD5SUMS.txt", code: 1},
-		{name: "empty-quote", hash: `""`, code: 1},
+		{name: "valid", args: []string{checksumFixtures["md5"].Valid}},
+		{name: "invalid", args: []string{checksumFixtures["md5"].Invalid}, code: 1},
+		{name: "filename", args: []string{checksumFixtures["md5"].Files.Valid}, code: 1},
+		{name: "empty-quote", args: []string{""}, code: 1},
 		{name: "none", code: 1},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
-			t.Run(fmt.Sprintf("%s-%s", shell, tc.name), func(t *testing.T) {
-				// t.Parallel()
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_is_md5hash %s", tc.hash))
-				libtest.PrintCmdDebug(t, cmd)
-
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC")
-
-				err := cmd.Run()
-				assert.Empty(t, stdoutBuf.String())
-				assert.Empty(t, stdoutBuf.String())
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
-				}
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tc.name), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_is_md5hash "$@"`,
+					libtest.WithArgs(tc.args...),
+					libtest.WithHermeticEnv(libtest.NewEnv(t, "TZ=UTC").WithLeakCheck()),
+				)
+				assert.Empty(t, r.Stdout)
+				assert.Empty(t, r.Stderr)
+				assert.Equal(t, tc.code, r.ExitCode)
 			})
 		}
 	}
@@ -56,37 +40,27 @@ func Test__libdl_is_md5hash(t *testing.T) {
 func Test__libdl_is_sha1hash(t *testing.T) {
 	// t.Parallel()
 
-	libtest.AssertShellsAvailable(t)
-
 	tests := []struct {
 		name string
 		code int
-		hash string
+		args []string
 	}{
-		{name: "valid", hash: SHA1_VALID},
-		{name: "invalid", hash: SHA1_INVALID, code: 1},
-		{name: "filename", hash: "testdata/SHA1SUMS.txt", code: 1},
-		{name: "empty-quote", hash: `""`, code: 1},
+		{name: "valid", args: []string{checksumFixtures["sha1"].Valid}},
+		{name: "invalid", args: []string{checksumFixtures["sha1"].Invalid}, code: 1},
+		{name: "filename", args: []string{checksumFixtures["sha1"].Files.Valid}, code: 1},
+		{name: "empty-quote", args: []string{""}, code: 1},
 		{name: "none", code: 1},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
-			t.Run(fmt.Sprintf("%s-%s", shell, tc.name), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_is_sha1hash %s", tc.hash))
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC")
-
-				err := cmd.Run()
-				assert.Empty(t, stdoutBuf.String())
-				assert.Empty(t, stdoutBuf.String())
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
-				}
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tc.name), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_is_sha1hash "$@"`,
+					libtest.WithArgs(tc.args...),
+					libtest.WithHermeticEnv(libtest.NewEnv(t, "TZ=UTC").WithLeakCheck()),
+				)
+				assert.Empty(t, r.Stdout)
+				assert.Empty(t, r.Stderr)
+				assert.Equal(t, tc.code, r.ExitCode)
 			})
 		}
 	}
@@ -95,37 +69,27 @@ func Test__libdl_is_sha1hash(t *testing.T) {
 func Test__libdl_is_sha256hash(t *testing.T) {
 	// t.Parallel()
 
-	libtest.AssertShellsAvailable(t)
-
 	tests := []struct {
 		name string
 		code int
-		hash string
+		args []string
 	}{
-		{name: "valid", hash: SHA256_VALID},
-		{name: "invalid", hash: SHA256_INVALID, code: 1},
-		{name: "empty-quote", hash: `""`, code: 1},
-		{name: "filename", hash: "testdata/SHA256SUMS.txt", code: 1},
+		{name: "valid", args: []string{checksumFixtures["sha256"].Valid}},
+		{name: "invalid", args: []string{checksumFixtures["sha256"].Invalid}, code: 1},
+		{name: "empty-quote", args: []string{""}, code: 1},
+		{name: "filename", args: []string{checksumFixtures["sha256"].Files.Valid}, code: 1},
 		{name: "none", code: 1},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
-			t.Run(fmt.Sprintf("%s-%s", shell, tc.name), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_is_sha256hash %s", tc.hash))
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC")
-
-				err := cmd.Run()
-				assert.Empty(t, stdoutBuf.String())
-				assert.Empty(t, stdoutBuf.String())
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
-				}
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tc.name), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_is_sha256hash "$@"`,
+					libtest.WithArgs(tc.args...),
+					libtest.WithHermeticEnv(libtest.NewEnv(t, "TZ=UTC").WithLeakCheck()),
+				)
+				assert.Empty(t, r.Stdout)
+				assert.Empty(t, r.Stderr)
+				assert.Equal(t, tc.code, r.ExitCode)
 			})
 		}
 	}
@@ -134,37 +98,27 @@ func Test__libdl_is_sha256hash(t *testing.T) {
 func Test__libdl_is_sha512hash(t *testing.T) {
 	// t.Parallel()
 
-	libtest.AssertShellsAvailable(t)
-
 	tests := []struct {
 		name string
 		code int
-		hash string
+		args []string
 	}{
-		{name: "valid", hash: SHA512_VALID},
-		{name: "invalid", hash: SHA512_INVALID, code: 1},
-		{name: "filename", hash: "testdata/SHA256SUMS.txt", code: 1},
-		{name: "empty-quote", hash: `""`, code: 1},
+		{name: "valid", args: []string{checksumFixtures["sha512"].Valid}},
+		{name: "invalid", args: []string{checksumFixtures["sha512"].Invalid}, code: 1},
+		{name: "filename", args: []string{checksumFixtures["sha256"].Files.Valid}, code: 1},
+		{name: "empty-quote", args: []string{""}, code: 1},
 		{name: "none", code: 1},
 	}
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, tc := range tests {
-			t.Run(fmt.Sprintf("%s-%s", shell, tc.name), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_is_sha512hash %s", tc.hash))
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC")
-
-				err := cmd.Run()
-				assert.Empty(t, stdoutBuf.String())
-				assert.Empty(t, stdoutBuf.String())
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-				if tc.code == 0 {
-					assert.Nil(t, err)
-				} else {
-					assert.NotNil(t, err)
-				}
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tc.name), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `__libdl_is_sha512hash "$@"`,
+					libtest.WithArgs(tc.args...),
+					libtest.WithHermeticEnv(libtest.NewEnv(t, "TZ=UTC").WithLeakCheck()),
+				)
+				assert.Empty(t, r.Stdout)
+				assert.Empty(t, r.Stderr)
+				assert.Equal(t, tc.code, r.ExitCode)
 			})
 		}
 	}
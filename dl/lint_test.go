@@ -0,0 +1,11 @@
+package dl
+
+import (
+	"testing"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func TestLint(t *testing.T) {
+	libtest.Lint(t, "dl")
+}
@@ -0,0 +1,80 @@
+package dl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// fakeUname returns a sandbox with uname reporting the system.
+func fakeUname(t *testing.T, system, machine string) *libtest.Sandbox {
+	t.Helper()
+
+	uname := libtest.FakeCommand(t, "uname",
+		libtest.FakeBehavior{Args: []string{"-s"}, Stdout: system + "\n"},
+		libtest.FakeBehavior{Args: []string{"-m"}, Stdout: machine + "\n"},
+	)
+	return uname.Sandbox()
+}
+
+func Test__shlib_explain_error_install_hints(t *testing.T) {
+	// t.Parallel()
+	tests := []struct {
+		code   int
+		system string
+		hint   string
+	}{
+		{code: 22, system: "Linux", hint: "sha256sum is provided by package coreutils"},
+		{code: 22, system: "Darwin", hint: "sha256sum is not available by default"},
+		{code: 23, system: "Linux", hint: "sha512sum is provided by package coreutils"},
+		{code: 24, system: "Darwin", hint: "sha1sum should be available by default"},
+		{code: 25, system: "Linux", hint: "md5sum is provided by package coreutils or busybox"},
+		{code: 26, system: "Darwin", hint: "brew install gnupg"},
+		{code: 26, system: "FreeBSD"},
+		{code: 27, system: "Linux", hint: "Commands gpg and gpgv are usually provided by package gnupg or gpg"},
+		{code: 27, system: "Darwin", hint: "brew install gnupg"},
+	}
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s=%d", shell.Name, tc.system, tc.code), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_explain_error "$@"`,
+					libtest.WithSandbox(fakeUname(t, tc.system, "x86_64")),
+					libtest.WithArgs(fmt.Sprint(tc.code)),
+				)
+				assert.Equal(t, tc.code, r.ExitCode)
+				assert.Empty(t, r.Stdout)
+				if tc.hint != "" {
+					assert.Contains(t, r.Stderr, tc.hint)
+				} else {
+					// only the error itself, without any hints.
+					assert.Equal(t, 1, strings.Count(r.Stderr, "\n"), "stderr: %s", r.Stderr)
+				}
+			})
+		}
+	}
+}
+
+func Test__shlib_explain_error_report_helper(t *testing.T) {
+	// t.Parallel()
+	for _, shell := range libtest.Shells(t) {
+		for _, code := range []int{11, 14, 15} {
+			t.Run(fmt.Sprintf("%s=%d", shell.Name, code), func(t *testing.T) {
+				r := libtest.Run(t, shell.Name, []string{"dl"}, `shlib_explain_error "$@"`,
+					libtest.WithSandbox(fakeUname(t, "Linux", "armv7l")),
+					libtest.WithArgs(fmt.Sprint(code)),
+					libtest.WithEnv("NO_COLOR=1"),
+				)
+				assert.Equal(t, code, r.ExitCode)
+				assert.Empty(t, r.Stdout)
+				assert.Contains(t, r.Stderr, "Please report this error to https://github.com/tprasadtp/shlibs")
+				assert.Contains(t, r.Stderr, "System Architecture (uname -m) : armv7l")
+				assert.Contains(t, r.Stderr, "Detected GOOS value            : linux")
+				assert.Contains(t, r.Stderr, "Detected GOARM value           : 7")
+				assert.NotContains(t, r.Stderr, "not found")
+			})
+		}
+	}
+}
@@ -1,10 +1,7 @@
 package dl
 
 import (
-	"bytes"
 	"fmt"
-	"os"
-	"os/exec"
 	"runtime"
 	"testing"
 
@@ -14,7 +11,6 @@ import (
 
 func Test__libdl_get_rendered_string(t *testing.T) {
 	// t.Parallel()
-	libtest.AssertShellsAvailable(t)
 
 	SYS_ARCH := libtest.UnameM()
 	SYS_OS := libtest.UnameS()
@@ -52,27 +48,22 @@ func Test__libdl_get_rendered_string(t *testing.T) {
 		},
 		{
 			name:   "with-all",
-			url:    "https://github.com/tprasadtp/gfilt/releases/download/v0.1.48/gfilt_++SYS_ARCH++_++SYS_ARCH++_++GOOS++_++GOARCH++.tar.gz",
+			url:    "https://github.com/tprasadtp/gfilt/releases/download/v0.1.48/gfilt_++SYS_OS++_++SYS_ARCH++_++GOOS++_++GOARCH++.tar.gz",
 			expect: fmt.Sprintf("https://github.com/tprasadtp/gfilt/releases/download/v0.1.48/gfilt_%s_%s_%s_%s.tar.gz", SYS_OS, SYS_ARCH, runtime.GOOS, runtime.GOARCH),
 		},
 	}
 	for _, shell := range []string{"bash"} {
 		for _, tc := range tests {
 			t.Run(fmt.Sprintf("%s-%s", shell, tc.name), func(t *testing.T) {
-				cmd := exec.Command(shell, "-c", fmt.Sprintf(". ./dl.sh && . ../logger/logger.sh && __libdl_render_template %s", tc.url))
-				libtest.PrintCmdDebug(t, cmd)
+				r := libtest.Run(t, shell, []string{"dl"}, `__libdl_render_template "$@"`,
+					libtest.WithArgs(tc.url),
+					libtest.WithEnv("TZ=UTC", "LOG_LVL=0"),
+				)
 
-				var stdoutBuf, stderrBuf bytes.Buffer
-				cmd.Stdout = &stdoutBuf
-				cmd.Stderr = &stderrBuf
-				cmd.Env = append(os.Environ(), "TZ=UTC", "LOG_LVL=0")
-				err := cmd.Run()
-
-				assert.Equal(t, tc.code, cmd.ProcessState.ExitCode())
-				assert.Equal(t, tc.expect, stdoutBuf.String())
+				assert.Equal(t, tc.code, r.ExitCode)
+				assert.Equal(t, tc.expect, r.Stdout)
 				// no logs are generated here
-				assert.Empty(t, stderrBuf.String())
-				assert.Nil(t, err)
+				assert.Empty(t, r.Stderr)
 			})
 		}
 	}
@@ -3,12 +3,18 @@ module github.com/tprasadtp/shlibs
 go 1.17
 
 require (
+	github.com/ProtonMail/go-crypto v0.0.0-20230426101702-58e86b294756
+	github.com/creack/pty v1.1.18
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/sergi/go-diff v1.2.0
 	github.com/stretchr/testify v1.8.0
+	mvdan.cc/sh/v3 v3.5.1
 )
 
 require (
+	github.com/cloudflare/circl v1.3.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	golang.org/x/crypto v0.7.0 // indirect
+	golang.org/x/sys v0.6.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,259 @@
+package apollo
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ansiBasicColors is the standard 16 color palette used by most terminals
+// (xterm defaults). Index 0-7 are normal colors and 8-15 are bright colors.
+var ansiBasicColors = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00",
+	"#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00",
+	"#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// ansiStyle holds the SGR state while rendering ANSI escape sequences.
+type ansiStyle struct {
+	fg        string
+	bg        string
+	bold      bool
+	dim       bool
+	italic    bool
+	underline bool
+}
+
+// css returns inline css for the style or empty string if the style is the
+// default style.
+func (s ansiStyle) css() string {
+	var b strings.Builder
+	if s.fg != "" {
+		fmt.Fprintf(&b, "color:%s;", s.fg)
+	}
+	if s.bg != "" {
+		fmt.Fprintf(&b, "background-color:%s;", s.bg)
+	}
+	if s.bold {
+		b.WriteString("font-weight:bold;")
+	}
+	if s.dim {
+		b.WriteString("opacity:0.7;")
+	}
+	if s.italic {
+		b.WriteString("font-style:italic;")
+	}
+	if s.underline {
+		b.WriteString("text-decoration:underline;")
+	}
+	return b.String()
+}
+
+// ansi256Color returns the hex color for the given xterm 256 color palette
+// index.
+func ansi256Color(n int) string {
+	switch {
+	case n < 0 || n > 255:
+		return ""
+	case n < 16:
+		return ansiBasicColors[n]
+	case n < 232:
+		n -= 16
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[(n/6)%6], levels[n%6])
+	default:
+		v := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+	}
+}
+
+// apply updates the style with given SGR parameters.
+func (s *ansiStyle) apply(params []int) {
+	if len(params) == 0 {
+		*s = ansiStyle{}
+		return
+	}
+
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			*s = ansiStyle{}
+		case p == 1:
+			s.bold = true
+		case p == 2:
+			s.dim = true
+		case p == 3:
+			s.italic = true
+		case p == 4:
+			s.underline = true
+		case p == 22:
+			s.bold, s.dim = false, false
+		case p == 23:
+			s.italic = false
+		case p == 24:
+			s.underline = false
+		case p >= 30 && p <= 37:
+			s.fg = ansiBasicColors[p-30]
+		case p >= 90 && p <= 97:
+			s.fg = ansiBasicColors[p-90+8]
+		case p >= 40 && p <= 47:
+			s.bg = ansiBasicColors[p-40]
+		case p >= 100 && p <= 107:
+			s.bg = ansiBasicColors[p-100+8]
+		case p == 39:
+			s.fg = ""
+		case p == 49:
+			s.bg = ""
+		case p == 38 || p == 48:
+			var color string
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				color = ansi256Color(params[i+2])
+				i += 2
+			case i+4 < len(params) && params[i+1] == 2:
+				color = fmt.Sprintf("#%02x%02x%02x", params[i+2]&0xff, params[i+3]&0xff, params[i+4]&0xff)
+				i += 4
+			}
+			if p == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+		}
+	}
+}
+
+// ansiToHTML converts text containing ANSI SGR escape sequences into HTML
+// with inline styles. Text is always HTML escaped. Escape sequences other than
+// SGR are dropped.
+func ansiToHTML(text string) string {
+	var b strings.Builder
+	var style ansiStyle
+	open := false
+
+	flush := func(segment string) {
+		if segment == "" {
+			return
+		}
+		b.WriteString(html.EscapeString(segment))
+	}
+
+	for {
+		idx := strings.IndexByte(text, 0x1b)
+		if idx < 0 {
+			flush(text)
+			break
+		}
+		flush(text[:idx])
+		text = text[idx:]
+
+		// Only CSI sequences (ESC [) are handled, others are dropped.
+		if len(text) < 2 || text[1] != '[' {
+			text = text[1:]
+			continue
+		}
+
+		end := 2
+		for end < len(text) && (text[end] < 0x40 || text[end] > 0x7e) {
+			end++
+		}
+		if end >= len(text) {
+			// unterminated sequence, drop it.
+			break
+		}
+
+		if text[end] == 'm' {
+			var params []int
+			if raw := text[2:end]; raw != "" {
+				for _, field := range strings.Split(raw, ";") {
+					n, err := strconv.Atoi(field)
+					if err != nil {
+						n = 0
+					}
+					params = append(params, n)
+				}
+			}
+			style.apply(params)
+
+			if open {
+				b.WriteString("</span>")
+				open = false
+			}
+			if css := style.css(); css != "" {
+				fmt.Fprintf(&b, `<span style="%s">`, css)
+				open = true
+			}
+		}
+		text = text[end+1:]
+	}
+
+	if open {
+		b.WriteString("</span>")
+	}
+	return b.String()
+}
+
+// ansiToVisible replaces the escape character with a visible symbol, so
+// that ANSI escape sequences can be seen in plain text outputs like
+// markdown code blocks.
+func ansiToVisible(text string) string {
+	return strings.ReplaceAll(text, "\x1b", "␛")
+}
+
+// normalizeANSI rewrites ANSI SGR escape sequences into a canonical form, so
+// that equivalent sequences compare equal. Empty parameters are written as
+// zero (`\e[m` becomes `\e[0m`), leading zeros are dropped (`\e[01;31m`
+// becomes `\e[1;31m`) and consecutive resets are collapsed into one.
+func normalizeANSI(d []byte) []byte {
+	if bytes.IndexByte(d, 0x1b) < 0 {
+		return d
+	}
+
+	out := make([]byte, 0, len(d))
+	lastReset := false
+	for i := 0; i < len(d); {
+		if d[i] != 0x1b || i+1 >= len(d) || d[i+1] != '[' {
+			out = append(out, d[i])
+			lastReset = false
+			i++
+			continue
+		}
+
+		end := i + 2
+		for end < len(d) && (d[end] < 0x40 || d[end] > 0x7e) {
+			end++
+		}
+		if end >= len(d) || d[end] != 'm' {
+			// not a SGR sequence, keep it as is.
+			if end >= len(d) {
+				end = len(d) - 1
+			}
+			out = append(out, d[i:end+1]...)
+			lastReset = false
+			i = end + 1
+			continue
+		}
+
+		var params []string
+		for _, field := range strings.Split(string(d[i+2:end]), ";") {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				n = 0
+			}
+			params = append(params, strconv.Itoa(n))
+		}
+
+		seq := "\x1b[" + strings.Join(params, ";") + "m"
+		reset := seq == "\x1b[0m"
+		if !(reset && lastReset) {
+			out = append(out, seq...)
+		}
+		lastReset = reset
+		i = end + 1
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package apollo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnsiToHTML(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"plain text": {
+			input:    "Hello World",
+			expected: "Hello World",
+		},
+		"escapes html": {
+			input:    "<b>&</b>",
+			expected: "&lt;b&gt;&amp;&lt;/b&gt;",
+		},
+		"basic color": {
+			input:    "\x1b[31mred\x1b[0m text",
+			expected: `<span style="color:#cd0000;">red</span> text`,
+		},
+		"256 color": {
+			input:    "\x1b[38;5;196mcritical\x1b[0m",
+			expected: `<span style="color:#ff0000;">critical</span>`,
+		},
+		"256 color grayscale": {
+			input:    "\x1b[38;5;246mtrace\x1b[0m",
+			expected: `<span style="color:#949494;">trace</span>`,
+		},
+		"true color background and bold": {
+			input:    "\x1b[1;48;2;1;2;3mx\x1b[m",
+			expected: `<span style="background-color:#010203;font-weight:bold;">x</span>`,
+		},
+		"unterminated span is closed": {
+			input:    "\x1b[32mgreen",
+			expected: `<span style="color:#00cd00;">green</span>`,
+		},
+		"non sgr sequences are dropped": {
+			input:    "\x1b[2Kclear",
+			expected: "clear",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ansiToHTML(test.input))
+		})
+	}
+}
+
+func TestAnsiToVisible(t *testing.T) {
+	assert.Equal(t, "␛[0mreset", ansiToVisible("\x1b[0mreset"))
+}
+
+func TestNormalizeANSI(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+	}{
+		"nil input":         {nil, nil},
+		"no escapes":        {[]byte("Hello"), []byte("Hello")},
+		"empty params":      {[]byte("\x1b[mHello"), []byte("\x1b[0mHello")},
+		"leading zeros":     {[]byte("\x1b[01;031mHello"), []byte("\x1b[1;31mHello")},
+		"repeated resets":   {[]byte("Hello\x1b[0m\x1b[m\x1b[0m"), []byte("Hello\x1b[0m")},
+		"non sgr preserved": {[]byte("\x1b[2KHello"), []byte("\x1b[2KHello")},
+		"256 colors":        {[]byte("\x1b[38;5;197mHello\x1b[0m"), []byte("\x1b[38;5;197mHello\x1b[0m")},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, normalizeANSI(test.input))
+		})
+	}
+}
@@ -6,6 +6,10 @@
 // and the test will fail if there is a difference.
 //
 // Updating the golden file can be done by running `go test -update ./...`.
+//
+// All the mismatches of a test run can be aggregated into a single HTML or
+// Markdown report by running `go test ./... -report=report.html`, provided
+// the package calls Run from its TestMain.
 package apollo
 
 import (
@@ -14,12 +18,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
-
-	"github.com/pmezard/go-difflib/difflib"
-	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
 const (
@@ -42,6 +44,29 @@ const (
 	// defaultDiffEngine sets which diff engine to use if not defined.
 	defaultDiffEngine = ClassicDiff
 
+	// defaultDiffContext is the number of context lines shown around
+	// changes by ClassicDiff and SideBySide engines.
+	defaultDiffContext = 1
+
+	// defaultDiffMaxLines is the maximum number of lines of diff output.
+	// Zero means diff output is not truncated.
+	defaultDiffMaxLines = 0
+
+	// defaultDiffWidth is the width of SideBySide diff output. Zero means
+	// width of the terminal is used.
+	defaultDiffWidth = 0
+
+	// defaultLineEndings sets the default value for the WithLineEndings
+	// option.
+	defaultLineEndings = Preserve
+
+	// defaultEncoding sets the default value for the WithEncoding option.
+	defaultEncoding = AnyEncoding
+
+	// defaultUsePlatformVariants sets the default value for the
+	// WithPlatformVariants option.
+	defaultUsePlatformVariants = false
+
 	// defaultIgnoreTemplateErrors sets the default value for the
 	// WithIgnoreTemplateErrors option.
 	defaultIgnoreTemplateErrors = false
@@ -66,6 +91,11 @@ var (
 	// test files.
 	clean = flag.Bool("clean", false, "Clean old golden test files before writing new olds")
 
+	// reportFile is the path of the report file aggregating all golden
+	// fixture mismatches of the test run. Report is only written if tests
+	// are run via Run from TestMain.
+	reportFile = flag.String("report", "", "Write report of golden fixture mismatches to file (.md for markdown, html otherwise)")
+
 	// ts saves the timestamp of the test run, we use ts to mark the
 	// modification time of golden file dirs, for cleaning if required by
 	// `-clean` flag.
@@ -82,9 +112,17 @@ type Apollo struct {
 
 	diffEngine           DiffEngine
 	diffFn               DiffFn
+	diffOptions          DiffOptions
 	ignoreTemplateErrors bool
 	useTestNameForDir    bool
 	useSubTestNameForDir bool
+	normalizeANSI        bool
+	lineEndings          LineEnding
+	encoding             Encoding
+	usePlatformVariants  bool
+	goos                 string
+	goarch               string
+	report               *Report
 }
 
 // Create new testers ==================================
@@ -93,56 +131,66 @@ type Apollo struct {
 // of the options, an error will be reported and t.FailNow() will be called.
 func New(t *testing.T, options ...Option) *Apollo {
 	a := Apollo{
-		fixtureDir:           defaultFixtureDir,
-		fileNameSuffix:       defaultFileNameSuffix,
-		filePerms:            defaultFilePerms,
-		dirPerms:             defaultDirPerms,
-		diffEngine:           defaultDiffEngine,
+		fixtureDir:     defaultFixtureDir,
+		fileNameSuffix: defaultFileNameSuffix,
+		filePerms:      defaultFilePerms,
+		dirPerms:       defaultDirPerms,
+		diffEngine:     defaultDiffEngine,
+		diffOptions: DiffOptions{
+			Context:  defaultDiffContext,
+			MaxLines: defaultDiffMaxLines,
+			Width:    defaultDiffWidth,
+		},
 		ignoreTemplateErrors: defaultIgnoreTemplateErrors,
+		lineEndings:          defaultLineEndings,
+		encoding:             defaultEncoding,
+		usePlatformVariants:  defaultUsePlatformVariants,
+		goos:                 runtime.GOOS,
+		goarch:               runtime.GOARCH,
 		useTestNameForDir:    defaultUseTestNameForDir,
 		useSubTestNameForDir: defaultUseSubTestNameForDir,
 	}
 
-	var err error
-	for _, option := range options {
-		err = option(&a)
-		if err != nil {
-			t.Error(fmt.Errorf("could not apply option: %w", err))
-			t.FailNow()
-		}
+	if *reportFile != "" {
+		a.report = defaultReport
 	}
 
+	a.apply(t, options...)
 	return &a
 }
 
-// Diff generates a string that shows the difference between the actual and the
-// expected. This method could be called in your own DiffFn in case you want
-// to leverage any of the engines defined.
-func Diff(engine DiffEngine, actual, expected string) (diff string) {
-	switch engine {
-	case Simple:
-		diff = fmt.Sprintf("Expected: %s\nGot: %s", expected, actual)
-
-	case ClassicDiff:
-		diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
-			A:        difflib.SplitLines(expected),
-			B:        difflib.SplitLines(actual),
-			FromFile: "Expected",
-			FromDate: "",
-			ToFile:   "Actual",
-			ToDate:   "",
-			Context:  1,
-		})
-
-	case ColoredDiff:
-		dmp := diffmatchpatch.New()
-		diffs := dmp.DiffMain(actual, expected, false)
-		diff = dmp.DiffPrettyText(diffs)
-	default:
-		diff = fmt.Sprintf("Expected: %s\nGot: %s", expected, actual)
-	}
-
-	return diff
+// With returns a copy of the tester with the given options applied on top of
+// the options of the parent. The parent tester is not modified. Unlike New,
+// it returns an error if any of the options is invalid, as it has no test to
+// report it to.
+//
+//	child, err := g.With(apollo.WithDiffEngine(apollo.SideBySide))
+func (a *Apollo) With(options ...Option) (*Apollo, error) {
+	child := *a
+	if err := child.applyOptions(options...); err != nil {
+		return nil, err
+	}
+	return &child, nil
+}
+
+// apply applies the options to the tester.
+func (a *Apollo) apply(t *testing.T, options ...Option) {
+	t.Helper()
+	if err := a.applyOptions(options...); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+}
+
+// applyOptions applies the options to the tester, stopping at the first
+// invalid option.
+func (a *Apollo) applyOptions(options ...Option) error {
+	for _, option := range options {
+		if err := option(a); err != nil {
+			return fmt.Errorf("could not apply option: %w", err)
+		}
+	}
+	return nil
 }
 
 // Update will update the golden fixtures with the received actual data.
@@ -150,14 +198,22 @@ func Diff(engine DiffEngine, actual, expected string) (diff string) {
 // This method does not need to be called from code, but it's exposed so that
 // it can be explicitly called if needed. The more common approach would be to
 // update using `go test -update ./...`.
+//
+// If platform variants are enabled, only the golden file of the current
+// platform is written.
 func (a *Apollo) Update(t *testing.T, name string, actualData []byte) error {
-	goldenFile := a.GoldenFileName(t, name)
+	var goldenFile string
+	if a.usePlatformVariants {
+		goldenFile = a.goldenFileName(t, name, a.platformKey())
+	} else {
+		goldenFile = a.GoldenFileName(t, name)
+	}
 	goldenFileDir := filepath.Dir(goldenFile)
 	if err := a.ensureDir(goldenFileDir); err != nil {
 		return err
 	}
 
-	if err := ioutil.WriteFile(goldenFile, actualData, a.filePerms); err != nil {
+	if err := ioutil.WriteFile(goldenFile, a.encode(a.normalize(actualData)), a.filePerms); err != nil {
 		return err
 	}
 
@@ -176,6 +232,10 @@ func (a *Apollo) ensureDir(loc string) error {
 		// the location does not exist, so make directories to there
 		return os.MkdirAll(loc, a.dirPerms)
 
+	case err == nil && s.IsDir() && *clean && s.ModTime().UnixNano() != ts.UnixNano() && a.usePlatformVariants:
+		// golden files of other platforms must be preserved.
+		return a.cleanPlatformFiles(loc)
+
 	case err == nil && s.IsDir() && *clean && s.ModTime().UnixNano() != ts.UnixNano():
 		if e2 := os.RemoveAll(loc); e2 != nil {
 			return e2
@@ -189,8 +249,51 @@ func (a *Apollo) ensureDir(loc string) error {
 	return err
 }
 
+// cleanPlatformFiles removes golden files of the current platform from the
+// directory.
+func (a *Apollo) cleanPlatformFiles(loc string) error {
+	files, err := filepath.Glob(filepath.Join(loc, fmt.Sprintf("*.%s%s", a.platformKey(), a.fileNameSuffix)))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GoldenFileName simply returns the file name of the golden file fixture.
+//
+// If platform variants are enabled, it returns the first existing file among
+// `name.GOOS-GOARCH<suffix>`, `name.GOOS<suffix>` and `name<suffix>`. If none
+// of them exist, the platform specific file name is returned.
 func (a *Apollo) GoldenFileName(t *testing.T, name string) string {
+	if !a.usePlatformVariants {
+		return a.goldenFileName(t, name, "")
+	}
+
+	for _, key := range []string{a.platformKey(), a.goos, ""} {
+		candidate := a.goldenFileName(t, name, key)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return a.goldenFileName(t, name, a.platformKey())
+}
+
+// platformKey returns the key used for platform specific golden files of the
+// current platform.
+func (a *Apollo) platformKey() string {
+	return fmt.Sprintf("%s-%s", a.goos, a.goarch)
+}
+
+// goldenFileName returns the file name of the golden file fixture with the
+// platform key. If key is empty, the generic file name is returned.
+func (a *Apollo) goldenFileName(t *testing.T, name, key string) string {
 	dir := a.fixtureDir
 
 	if a.useTestNameForDir {
@@ -204,5 +307,9 @@ func (a *Apollo) GoldenFileName(t *testing.T, name string) string {
 		}
 	}
 
+	if key != "" {
+		name = fmt.Sprintf("%s.%s", name, key)
+	}
+
 	return filepath.Join(dir, fmt.Sprintf("%s%s", name, a.fileNameSuffix))
 }
@@ -300,3 +300,61 @@ func TestCleanFunction(t *testing.T) {
 	*clean = savedCleanState
 	*update = savedUpdateState
 }
+
+func TestPlatformVariants(t *testing.T) {
+	dir := t.TempDir()
+	a := New(t, WithFixtureDir(dir), WithPlatformVariants(true))
+	a.goos = "linux"
+	a.goarch = "arm64"
+
+	generic := filepath.Join(dir, "example"+defaultFileNameSuffix)
+	goos := filepath.Join(dir, "example.linux"+defaultFileNameSuffix)
+	platform := filepath.Join(dir, "example.linux-arm64"+defaultFileNameSuffix)
+	other := filepath.Join(dir, "example.linux-amd64"+defaultFileNameSuffix)
+
+	// nothing exists, platform specific file name is returned
+	assert.Equal(t, platform, a.GoldenFileName(t, "example"))
+
+	require.NoError(t, ioutil.WriteFile(other, []byte("amd64"), a.filePerms))
+	require.NoError(t, ioutil.WriteFile(generic, []byte("generic"), a.filePerms))
+	assert.Equal(t, generic, a.GoldenFileName(t, "example"))
+	assert.Nil(t, a.compare(t, "example", []byte("generic")))
+
+	require.NoError(t, ioutil.WriteFile(goos, []byte("linux"), a.filePerms))
+	assert.Equal(t, goos, a.GoldenFileName(t, "example"))
+
+	// update writes only the current platform key
+	require.NoError(t, a.Update(t, "example", []byte("arm64")))
+	assert.Equal(t, platform, a.GoldenFileName(t, "example"))
+	assert.Nil(t, a.compare(t, "example", []byte("arm64")))
+
+	for file, content := range map[string]string{
+		generic: "generic", goos: "linux", other: "amd64", platform: "arm64",
+	} {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+}
+
+func TestCleanPlatformFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := New(t, WithFixtureDir(dir), WithPlatformVariants(true))
+	a.goos = "linux"
+	a.goarch = "arm64"
+
+	files := map[string]bool{
+		"example.linux-arm64" + defaultFileNameSuffix: false,
+		"example.linux-amd64" + defaultFileNameSuffix: true,
+		"example" + defaultFileNameSuffix:             true,
+	}
+	for file := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), nil, a.filePerms))
+	}
+
+	require.NoError(t, a.cleanPlatformFiles(dir))
+	for file, exists := range files {
+		_, err := os.Stat(filepath.Join(dir, file))
+		assert.Equal(t, exists, err == nil, file)
+	}
+}
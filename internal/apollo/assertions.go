@@ -10,6 +10,7 @@ import (
 	"os"
 	"testing"
 	"text/template"
+	"unicode/utf8"
 )
 
 // Assert compares the actual data received with the expected data in the
@@ -146,7 +147,8 @@ func (a *Apollo) AssertWithTemplate(t *testing.T, name string, data interface{},
 // compare is reading the golden fixture file and compare the stored data with
 // the actual data.
 func (a *Apollo) compare(t *testing.T, name string, actualData []byte) error {
-	expectedData, err := ioutil.ReadFile(a.GoldenFileName(t, name))
+	goldenFile := a.GoldenFileName(t, name)
+	expectedData, err := ioutil.ReadFile(goldenFile)
 
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -156,27 +158,21 @@ func (a *Apollo) compare(t *testing.T, name string, actualData []byte) error {
 		return fmt.Errorf("expected %s to be nil", err.Error())
 	}
 
-	if !bytes.Equal(actualData, expectedData) {
-		msg := "Result did not match the golden fixture. Diff is below:\n\n"
-		actual := string(actualData)
-		expected := string(expectedData)
-
-		if a.diffFn != nil {
-			msg += a.diffFn(actual, expected)
-		} else {
-			msg += Diff(a.diffEngine, actual, expected)
-		}
-
-		return newErrFixtureMismatch(msg)
+	if err := a.checkGolden(goldenFile, expectedData); err != nil {
+		return err
 	}
 
-	return nil
+	actualData = a.normalize(actualData)
+	expectedData = a.normalize(expectedData)
+
+	return a.check(t, goldenFile, actualData, expectedData)
 }
 
 // compareTemplate is reading the golden fixture file and compare the stored
 // data with the actual data.
 func (a *Apollo) compareTemplate(t *testing.T, name string, data interface{}, actualData []byte) error {
-	expectedDataTmpl, err := ioutil.ReadFile(a.GoldenFileName(t, name))
+	goldenFile := a.GoldenFileName(t, name)
+	expectedDataTmpl, err := ioutil.ReadFile(goldenFile)
 
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -186,6 +182,10 @@ func (a *Apollo) compareTemplate(t *testing.T, name string, data interface{}, ac
 		return fmt.Errorf("expected %s to be nil", err.Error())
 	}
 
+	if err := a.checkGolden(goldenFile, expectedDataTmpl); err != nil {
+		return err
+	}
+
 	missingKey := "error"
 	if a.ignoreTemplateErrors {
 		missingKey = "default"
@@ -202,19 +202,110 @@ func (a *Apollo) compareTemplate(t *testing.T, name string, data interface{}, ac
 		return newErrMissingKey(fmt.Sprintf("Template error: %s", err.Error()))
 	}
 
-	if !bytes.Equal(actualData, expectedData.Bytes()) {
-		msg := "Result did not match the golden fixture. Diff is below:\n\n"
-		actual := string(actualData)
-		expected := expectedData.String()
+	actualData = a.normalize(actualData)
+	expectedBytes := a.normalize(expectedData.Bytes())
 
-		if a.diffFn != nil {
-			msg += a.diffFn(actual, expected)
-		} else {
-			msg += Diff(a.diffEngine, actual, expected)
-		}
+	return a.check(t, goldenFile, actualData, expectedBytes)
+}
 
-		return newErrFixtureMismatch(msg)
+// checkGolden checks the golden file data against the encoding policy,
+// before it is normalized.
+func (a *Apollo) checkGolden(goldenFile string, golden []byte) error {
+	if a.encoding == AnyEncoding {
+		return nil
+	}
+	if !utf8.Valid(golden) {
+		return newErrInvalidEncoding(fmt.Sprintf("golden file %s is not valid UTF-8", goldenFile))
+	}
+	if a.encoding == UTF8BOM && !hasBOM(golden) {
+		return newErrInvalidEncoding(fmt.Sprintf(
+			"golden file %s has no byte order mark, which is required by UTF8BOM encoding", goldenFile))
 	}
-
 	return nil
 }
+
+// check compares the normalized actual and expected data and returns an
+// error describing the mismatch, if any. Differences only in line endings or
+// byte order mark are reported as such, instead of a diff which would look
+// identical.
+func (a *Apollo) check(t *testing.T, goldenFile string, actualData, expectedData []byte) error {
+	if a.encoding != AnyEncoding && !utf8.Valid(actualData) {
+		return newErrInvalidEncoding("actual data is not valid UTF-8")
+	}
+
+	if bytes.Equal(actualData, expectedData) {
+		return nil
+	}
+
+	actual := string(actualData)
+	expected := string(expectedData)
+	a.record(t, goldenFile, expected, actual)
+
+	switch {
+	case hasBOM(actualData) != hasBOM(expectedData) && bytes.Equal(stripBOM(actualData), stripBOM(expectedData)):
+		return newErrFixtureMismatch(fmt.Sprintf(
+			"Result did not match the golden fixture: byte order mark differs (expected: %s, actual: %s)",
+			describeBOM(expectedData), describeBOM(actualData)))
+
+	case bytes.Equal(toLF(actualData), toLF(expectedData)):
+		return newErrFixtureMismatch(fmt.Sprintf(
+			"Result did not match the golden fixture: line endings differ (expected: %s, actual: %s)",
+			describeLineEndings(expectedData), describeLineEndings(actualData)))
+	}
+
+	msg := "Result did not match the golden fixture. Diff is below:\n\n"
+	msg += a.diff(goldenFile, actual, expected)
+	return newErrFixtureMismatch(msg)
+}
+
+// record adds the mismatch to the report, if reporting is enabled.
+func (a *Apollo) record(t *testing.T, goldenFile, expected, actual string) {
+	if a.report == nil {
+		return
+	}
+
+	a.report.Add(ReportEntry{
+		Test:       t.Name(),
+		GoldenFile: goldenFile,
+		Expected:   expected,
+		Actual:     actual,
+	})
+}
+
+// diff returns the diff of actual and expected using the DiffFn if defined,
+// otherwise the configured diff engine.
+func (a *Apollo) diff(goldenFile, actual, expected string) string {
+	if a.diffFn != nil {
+		return a.diffFn(actual, expected, goldenFile, a.diffOptions)
+	}
+	return DiffWithOptions(a.diffEngine, actual, expected, a.diffOptions)
+}
+
+// normalize applies the configured normalizations to the data before it is
+// compared or written to the golden file.
+func (a *Apollo) normalize(d []byte) []byte {
+	if a.normalizeANSI {
+		d = normalizeANSI(d)
+	}
+
+	if a.encoding == UTF8BOM {
+		d = stripBOM(d)
+	}
+
+	switch a.lineEndings {
+	case LF:
+		d = toLF(d)
+	case CRLF:
+		d = toCRLF(d)
+	}
+	return d
+}
+
+// encode applies the encoding policy to the normalized data before it is
+// written to the golden file.
+func (a *Apollo) encode(d []byte) []byte {
+	if a.encoding == UTF8BOM {
+		return append(append([]byte{}, utf8BOM...), d...)
+	}
+	return d
+}
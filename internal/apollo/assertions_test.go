@@ -1,11 +1,13 @@
 package apollo
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCompare(t *testing.T) {
@@ -148,3 +150,133 @@ func TestNormalizeLF(t *testing.T) {
 		})
 	}
 }
+
+func TestComparePolicies(t *testing.T) {
+	tests := map[string]struct {
+		options  []Option
+		golden   []byte
+		actual   []byte
+		err      error
+		is       error
+		contains string
+	}{
+		"preserve identical": {
+			golden: []byte("a\nb\n"),
+			actual: []byte("a\nb\n"),
+		},
+		"preserve line endings differ": {
+			golden:   []byte("a\r\nb\r\n"),
+			actual:   []byte("a\nb\n"),
+			err:      &errFixtureMismatch{},
+			contains: "line endings differ (expected: CRLF, actual: LF)",
+		},
+		"lf with crlf golden": {
+			options: []Option{WithLineEndings(LF)},
+			golden:  []byte("a\r\nb\r\n"),
+			actual:  []byte("a\nb\n"),
+		},
+		"crlf with lf actual": {
+			options: []Option{WithLineEndings(CRLF)},
+			golden:  []byte("a\r\nb\r\n"),
+			actual:  []byte("a\nb\n"),
+		},
+		"lf with real difference": {
+			options:  []Option{WithLineEndings(LF)},
+			golden:   []byte("a\r\nb\r\n"),
+			actual:   []byte("a\nc\n"),
+			err:      &errFixtureMismatch{},
+			contains: "Diff is below",
+		},
+		"utf8 bom differs": {
+			options:  []Option{WithEncoding(UTF8)},
+			golden:   []byte("\xef\xbb\xbfa\n"),
+			actual:   []byte("a\n"),
+			err:      &errFixtureMismatch{},
+			contains: "byte order mark differs (expected: present, actual: absent)",
+		},
+		"utf8 bom ignored": {
+			options: []Option{WithEncoding(UTF8BOM)},
+			golden:  []byte("\xef\xbb\xbfa\n"),
+			actual:  []byte("a\n"),
+		},
+		"utf8 bom missing from golden": {
+			options:  []Option{WithEncoding(UTF8BOM)},
+			golden:   []byte("a\n"),
+			actual:   []byte("a\n"),
+			is:       ErrInvalidEncoding,
+			contains: "has no byte order mark",
+		},
+		"utf8 invalid golden": {
+			options:  []Option{WithEncoding(UTF8)},
+			golden:   []byte("\xffa\n"),
+			actual:   []byte("a\n"),
+			is:       ErrInvalidEncoding,
+			contains: "is not valid UTF-8",
+		},
+		"utf8 invalid actual": {
+			options: []Option{WithEncoding(UTF8)},
+			golden:  []byte("a\n"),
+			actual:  []byte("\xffa\n"),
+			is:      ErrInvalidEncoding,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := New(t, append([]Option{WithFixtureDir(t.TempDir())}, test.options...)...)
+
+			err := ioutil.WriteFile(a.GoldenFileName(t, "policy"), test.golden, a.filePerms)
+			require.NoError(t, err)
+
+			err = a.compare(t, "policy", test.actual)
+			if test.is != nil {
+				assert.ErrorIs(t, err, test.is)
+			} else {
+				assert.IsType(t, test.err, err)
+			}
+			if test.contains != "" {
+				assert.Contains(t, err.Error(), test.contains)
+			}
+		})
+	}
+}
+
+func TestUpdatePolicies(t *testing.T) {
+	tests := map[string]struct {
+		options []Option
+		actual  []byte
+		golden  []byte
+	}{
+		"preserve": {
+			actual: []byte("a\r\nb\n"),
+			golden: []byte("a\r\nb\n"),
+		},
+		"lf": {
+			options: []Option{WithLineEndings(LF)},
+			actual:  []byte("a\r\nb\n"),
+			golden:  []byte("a\nb\n"),
+		},
+		"crlf": {
+			options: []Option{WithLineEndings(CRLF)},
+			actual:  []byte("a\r\nb\n"),
+			golden:  []byte("a\r\nb\r\n"),
+		},
+		"utf8 with bom": {
+			options: []Option{WithEncoding(UTF8BOM)},
+			actual:  []byte("a\n"),
+			golden:  []byte("\xef\xbb\xbfa\n"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := New(t, append([]Option{WithFixtureDir(t.TempDir())}, test.options...)...)
+
+			require.NoError(t, a.Update(t, "policy", test.actual))
+			data, err := ioutil.ReadFile(a.GoldenFileName(t, "policy"))
+			require.NoError(t, err)
+			assert.Equal(t, test.golden, data)
+			assert.Nil(t, a.compare(t, "policy", test.actual))
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package apollo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// fallbackTerminalWidth is used by SideBySide engine when width of the
+// terminal cannot be determined.
+const fallbackTerminalWidth = 120
+
+// DiffOptions controls the output of diff engines.
+type DiffOptions struct {
+	// Context is the number of unchanged lines shown around the changes.
+	// Only applies to ClassicDiff and SideBySide engines.
+	Context int
+
+	// MaxLines is the maximum number of lines of the diff output. Rest of
+	// the output is replaced with a "… N more lines" marker. Zero means
+	// unlimited.
+	MaxLines int
+
+	// Width is the total width of the SideBySide output. Zero means width
+	// of the terminal, $COLUMNS or 120 columns in that order.
+	Width int
+}
+
+// Diff generates a string that shows the difference between the actual and the
+// expected. This method could be called in your own DiffFn in case you want
+// to leverage any of the engines defined.
+func Diff(engine DiffEngine, actual, expected string) string {
+	return DiffWithOptions(engine, actual, expected, DiffOptions{
+		Context:  defaultDiffContext,
+		MaxLines: defaultDiffMaxLines,
+		Width:    defaultDiffWidth,
+	})
+}
+
+// DiffWithOptions is like Diff, but allows controlling context lines,
+// truncation and width of the output.
+func DiffWithOptions(engine DiffEngine, actual, expected string, opts DiffOptions) (diff string) {
+	switch engine {
+	case Simple:
+		diff = fmt.Sprintf("Expected: %s\nGot: %s", expected, actual)
+
+	case ClassicDiff:
+		diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(expected),
+			B:        difflib.SplitLines(actual),
+			FromFile: "Expected",
+			FromDate: "",
+			ToFile:   "Actual",
+			ToDate:   "",
+			Context:  opts.Context,
+		})
+
+	case ColoredDiff:
+		dmp := diffmatchpatch.New()
+		diffs := dmp.DiffMain(actual, expected, false)
+		diff = dmp.DiffPrettyText(diffs)
+
+	case SideBySide:
+		diff = sideBySide(actual, expected, opts)
+
+	case VisibleDiff:
+		diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(makeVisible(expected)),
+			B:        difflib.SplitLines(makeVisible(actual)),
+			FromFile: "Expected",
+			FromDate: "",
+			ToFile:   "Actual",
+			ToDate:   "",
+			Context:  opts.Context,
+		})
+
+	default:
+		diff = fmt.Sprintf("Expected: %s\nGot: %s", expected, actual)
+	}
+
+	return truncateLines(diff, opts.MaxLines)
+}
+
+// truncateLines limits the text to max lines and appends a marker with
+// number of lines omitted. If max is zero or less, text is returned as is.
+func truncateLines(text string, max int) string {
+	if max <= 0 {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= max {
+		return text
+	}
+
+	out := strings.Join(lines[:max], "")
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out + fmt.Sprintf("… %d more lines\n", len(lines)-max)
+}
+
+// makeVisible replaces escape characters, carriage returns and tabs with
+// visible symbols and marks trailing spaces with a middle dot.
+func makeVisible(text string) string {
+	text = strings.NewReplacer("\x1b", "␛", "\r", "␍", "\t", "␉").Replace(text)
+
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		trimmed := strings.TrimRight(body, " ")
+		if n := len(body) - len(trimmed); n > 0 {
+			lines[i] = trimmed + strings.Repeat("·", n) + line[len(body):]
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// sideBySideRow is a single row of a side by side diff. Line numbers are
+// zero if the line does not exist on that side.
+type sideBySideRow struct {
+	Op         byte
+	ExpectedNo int
+	Expected   string
+	ActualNo   int
+	Actual     string
+}
+
+// splitLines splits the text into lines without line terminators.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// sideBySideRows builds the rows of side by side diff of expected and actual.
+// Op is one of 'e' (equal), 'r' (replace), 'd' (delete) or 'i' (insert).
+func sideBySideRows(expected, actual string) []sideBySideRow {
+	a := splitLines(expected)
+	b := splitLines(actual)
+	return opCodeRows(a, b, difflib.NewMatcher(a, b).GetOpCodes())
+}
+
+// opCodeRows builds side by side diff rows from the opcodes.
+func opCodeRows(a, b []string, ops []difflib.OpCode) []sideBySideRow {
+	var rows []sideBySideRow
+	for _, op := range ops {
+		n := op.I2 - op.I1
+		if m := op.J2 - op.J1; m > n {
+			n = m
+		}
+		for k := 0; k < n; k++ {
+			row := sideBySideRow{Op: op.Tag}
+			if i := op.I1 + k; i < op.I2 {
+				row.ExpectedNo = i + 1
+				row.Expected = a[i]
+			}
+			if j := op.J1 + k; j < op.J2 {
+				row.ActualNo = j + 1
+				row.Actual = b[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// sideBySide renders expected and actual in two columns similar to
+// `diff --side-by-side`. Escape characters are made visible, as they would
+// otherwise break the alignment of columns.
+func sideBySide(actual, expected string, opts DiffOptions) string {
+	width := opts.Width
+	if width <= 0 {
+		width = terminalWidth()
+	}
+
+	col := (width - 3) / 2
+	if col < 8 {
+		col = 8
+	}
+
+	a := splitLines(ansiToVisible(expected))
+	b := splitLines(ansiToVisible(actual))
+
+	var out strings.Builder
+	writeColumns(&out, col, "Expected", ' ', "Actual")
+	writeColumns(&out, col, strings.Repeat("-", col), ' ', strings.Repeat("-", col))
+
+	context := opts.Context
+	if context < 0 {
+		context = 0
+	}
+
+	for i, group := range difflib.NewMatcher(a, b).GetGroupedOpCodes(context) {
+		if i > 0 {
+			writeColumns(&out, col, "~", ' ', "~")
+		}
+		for _, row := range opCodeRows(a, b, group) {
+			var marker byte
+			switch row.Op {
+			case 'r':
+				marker = '|'
+			case 'd':
+				marker = '<'
+			case 'i':
+				marker = '>'
+			default:
+				marker = ' '
+			}
+			writeColumns(&out, col, row.Expected, marker, row.Actual)
+		}
+	}
+
+	return out.String()
+}
+
+// writeColumns writes left and right columns separated by marker. Columns
+// longer than col runes are truncated with an ellipsis.
+func writeColumns(out *strings.Builder, col int, left string, marker byte, right string) {
+	left = fitColumn(left, col)
+	line := left + strings.Repeat(" ", col-utf8.RuneCountInString(left)) +
+		" " + string(marker) + " " + fitColumn(right, col)
+	out.WriteString(strings.TrimRight(line, " "))
+	out.WriteByte('\n')
+}
+
+// fitColumn truncates s to n runes, replacing the last rune with an ellipsis
+// if it was truncated. Tabs are expanded to single spaces to keep alignment.
+func fitColumn(s string, n int) string {
+	s = strings.ReplaceAll(s, "\t", " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+// terminalWidth returns width of the terminal attached to stdout or stderr,
+// then $COLUMNS, falling back to fallbackTerminalWidth.
+func terminalWidth() int {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if w := fileTerminalWidth(f); w > 0 {
+			return w
+		}
+	}
+
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+
+	return fallbackTerminalWidth
+}
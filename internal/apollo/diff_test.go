@@ -0,0 +1,199 @@
+package apollo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffWithOptions(t *testing.T) {
+	expected := "a\nb\nc\nd\ne\nf\ng\n"
+	actual := "a\nb\nc\nD\ne\nf\ng\n"
+
+	tests := map[string]struct {
+		engine DiffEngine
+		opts   DiffOptions
+		diff   string
+	}{
+		"classic with context 0": {
+			engine: ClassicDiff,
+			opts:   DiffOptions{Context: 0},
+			diff: `--- Expected
++++ Actual
+@@ -4 +4 @@
+-d
++D
+`,
+		},
+		"classic with context 2": {
+			engine: ClassicDiff,
+			opts:   DiffOptions{Context: 2},
+			diff: `--- Expected
++++ Actual
+@@ -2,5 +2,5 @@
+ b
+ c
+-d
++D
+ e
+ f
+`,
+		},
+		"classic truncated": {
+			engine: ClassicDiff,
+			opts:   DiffOptions{Context: 2, MaxLines: 4},
+			diff: `--- Expected
++++ Actual
+@@ -2,5 +2,5 @@
+ b
+… 5 more lines
+`,
+		},
+		"simple truncated": {
+			engine: Simple,
+			opts:   DiffOptions{MaxLines: 2},
+			diff: `Expected: a
+b
+… 13 more lines
+`,
+		},
+		"side by side": {
+			engine: SideBySide,
+			opts:   DiffOptions{Context: 1, Width: 23},
+			diff: `Expected     Actual
+----------   ----------
+c            c
+d          | D
+e            e
+`,
+		},
+		"side by side groups": {
+			engine: SideBySide,
+			opts:   DiffOptions{Context: 0, Width: 23},
+			diff: `Expected     Actual
+----------   ----------
+d          | D
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.diff, DiffWithOptions(test.engine, actual, expected, test.opts))
+		})
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	tests := map[string]struct {
+		actual   string
+		expected string
+		diff     string
+	}{
+		"insert and delete": {
+			expected: "a\nb\n",
+			actual:   "b\nc\n",
+			diff: `Expected     Actual
+----------   ----------
+a          <
+b            b
+           > c
+`,
+		},
+		"long lines and escapes": {
+			expected: "\x1b[31mred and long\x1b[0m\n",
+			actual:   "plain\n",
+			diff: `Expected     Actual
+----------   ----------
+␛[31mred … | plain
+`,
+		},
+		"multiple groups": {
+			expected: "1\n2\n3\n4\n5\n",
+			actual:   "0\n2\n3\n4\n6\n",
+			diff: `Expected     Actual
+----------   ----------
+1          | 0
+2            2
+~            ~
+4            4
+5          | 6
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.diff, sideBySide(test.actual, test.expected, DiffOptions{Context: 1, Width: 23}))
+		})
+	}
+}
+
+func TestMakeVisible(t *testing.T) {
+	assert.Equal(t,
+		"␛[31mred␛[0m··\nwindows␍\n␉tab\n",
+		makeVisible("\x1b[31mred\x1b[0m  \nwindows\r\n\ttab\n"),
+	)
+}
+
+func TestVisibleDiff(t *testing.T) {
+	expected := "[ERROR] message \n"
+	actual := "[ERROR] message\n"
+	assert.Equal(t, `--- Expected
++++ Actual
+@@ -1,2 +1,2 @@
+-[ERROR] message·
++[ERROR] message
+ 
+`, DiffWithOptions(VisibleDiff, actual, expected, DiffOptions{Context: 1}))
+}
+
+func TestSideBySideRows(t *testing.T) {
+	rows := sideBySideRows("a\nb\nc\n", "a\nB\nc\nd\n")
+	expected := []sideBySideRow{
+		{Op: 'e', ExpectedNo: 1, Expected: "a", ActualNo: 1, Actual: "a"},
+		{Op: 'r', ExpectedNo: 2, Expected: "b", ActualNo: 2, Actual: "B"},
+		{Op: 'e', ExpectedNo: 3, Expected: "c", ActualNo: 3, Actual: "c"},
+		{Op: 'i', ActualNo: 4, Actual: "d"},
+	}
+	assert.Equal(t, expected, rows)
+}
+
+func TestTruncateLines(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		max      int
+		expected string
+	}{
+		"unlimited":        {"a\nb\nc\n", 0, "a\nb\nc\n"},
+		"within limit":     {"a\nb\nc\n", 3, "a\nb\nc\n"},
+		"truncated":        {"a\nb\nc\n", 1, "a\n… 2 more lines\n"},
+		"no trailing line": {"a\nb\nc", 2, "a\nb\n… 1 more lines\n"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, truncateLines(test.input, test.max))
+		})
+	}
+}
+
+func TestDiffFnReceivesGoldenFileAndOptions(t *testing.T) {
+	var gotFile string
+	var gotOpts DiffOptions
+
+	a := New(t,
+		WithDiffContext(3),
+		WithDiffMaxLines(10),
+		WithDiffWidth(80),
+		WithDiffFn(func(actual, expected, goldenFile string, opts DiffOptions) string {
+			gotFile = goldenFile
+			gotOpts = opts
+			return "custom"
+		}),
+	)
+
+	assert.Equal(t, "custom", a.diff("testdata/example.golden.txt", "a", "b"))
+	assert.Equal(t, "testdata/example.golden.txt", gotFile)
+	assert.Equal(t, DiffOptions{Context: 3, MaxLines: 10, Width: 80}, gotOpts)
+}
@@ -0,0 +1,60 @@
+package apollo
+
+import "bytes"
+
+// utf8BOM is the UTF-8 byte order mark.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// hasBOM returns true if the data starts with UTF-8 byte order mark.
+func hasBOM(d []byte) bool {
+	return bytes.HasPrefix(d, utf8BOM)
+}
+
+// stripBOM removes the UTF-8 byte order mark from the data, if present.
+func stripBOM(d []byte) []byte {
+	return bytes.TrimPrefix(d, utf8BOM)
+}
+
+// describeBOM returns a human readable description of byte order mark in
+// the data.
+func describeBOM(d []byte) string {
+	if hasBOM(d) {
+		return "present"
+	}
+	return "absent"
+}
+
+// toLF converts CRLF line endings to LF. Unlike normalizeLF, lone CR
+// characters are preserved, as they are often meaningful in shell output.
+func toLF(d []byte) []byte {
+	if bytes.IndexByte(d, '\r') < 0 {
+		return d
+	}
+	return bytes.ReplaceAll(d, []byte("\r\n"), []byte("\n"))
+}
+
+// toCRLF converts all LF and CRLF line endings to CRLF.
+func toCRLF(d []byte) []byte {
+	if bytes.IndexByte(d, '\n') < 0 {
+		return d
+	}
+	return bytes.ReplaceAll(toLF(d), []byte("\n"), []byte("\r\n"))
+}
+
+// describeLineEndings returns a human readable description of line endings
+// used in the data.
+func describeLineEndings(d []byte) string {
+	crlf := bytes.Count(d, []byte("\r\n"))
+	lf := bytes.Count(d, []byte("\n")) - crlf
+
+	switch {
+	case crlf == 0 && lf == 0:
+		return "none"
+	case crlf == 0:
+		return "LF"
+	case lf == 0:
+		return "CRLF"
+	default:
+		return "mixed"
+	}
+}
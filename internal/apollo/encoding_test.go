@@ -0,0 +1,42 @@
+package apollo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineEndingConversions(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		lf    []byte
+		crlf  []byte
+		desc  string
+	}{
+		"nil input":   {nil, nil, nil, "none"},
+		"no newlines": {[]byte("Hello"), []byte("Hello"), []byte("Hello"), "none"},
+		"unix":        {[]byte("Hello\nWorld\n"), []byte("Hello\nWorld\n"), []byte("Hello\r\nWorld\r\n"), "LF"},
+		"windows":     {[]byte("Hello\r\nWorld\r\n"), []byte("Hello\nWorld\n"), []byte("Hello\r\nWorld\r\n"), "CRLF"},
+		"mixed":       {[]byte("Hello\r\nWorld\n"), []byte("Hello\nWorld\n"), []byte("Hello\r\nWorld\r\n"), "mixed"},
+		"lone cr":     {[]byte("50%\r100%\n"), []byte("50%\r100%\n"), []byte("50%\r100%\r\n"), "LF"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.lf, toLF(test.input))
+			assert.Equal(t, test.crlf, toCRLF(test.input))
+			assert.Equal(t, test.desc, describeLineEndings(test.input))
+		})
+	}
+}
+
+func TestBOM(t *testing.T) {
+	withBOM := []byte("\xef\xbb\xbfHello")
+
+	assert.True(t, hasBOM(withBOM))
+	assert.False(t, hasBOM([]byte("Hello")))
+	assert.Equal(t, []byte("Hello"), stripBOM(withBOM))
+	assert.Equal(t, []byte("Hello"), stripBOM([]byte("Hello")))
+	assert.Equal(t, "present", describeBOM(withBOM))
+	assert.Equal(t, "absent", describeBOM(nil))
+}
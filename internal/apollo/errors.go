@@ -1,6 +1,19 @@
 package apollo
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidOption is wrapped by the errors returned for options given an
+	// invalid value, like those of With.
+	ErrInvalidOption = errors.New("invalid option")
+
+	// ErrInvalidEncoding is wrapped by the errors returned when the actual
+	// data or the golden file does not match the encoding policy.
+	ErrInvalidEncoding = errors.New("invalid encoding")
+)
 
 // errFixtureNotFound is thrown when the fixture file could not be found.
 type errFixtureNotFound struct {
@@ -72,3 +85,55 @@ func newErrMissingKey(message string) *errMissingKey {
 func (e *errMissingKey) Error() string {
 	return e.message
 }
+
+// errInvalidOption is thrown when an option is given an invalid value.
+type errInvalidOption struct {
+	option string
+	value  interface{}
+	reason string
+}
+
+// newErrInvalidOption returns a new instance of the error.
+func newErrInvalidOption(option string, value interface{}, reason string) *errInvalidOption {
+	return &errInvalidOption{
+		option: option,
+		value:  value,
+		reason: reason,
+	}
+}
+
+func (e *errInvalidOption) Error() string {
+	return fmt.Sprintf("invalid value for %s(%v): %s", e.option, e.value, e.reason)
+}
+
+// Option returns the name of the option which was given an invalid value.
+func (e *errInvalidOption) Option() string {
+	return e.option
+}
+
+// Unwrap returns ErrInvalidOption.
+func (e *errInvalidOption) Unwrap() error {
+	return ErrInvalidOption
+}
+
+// errInvalidEncoding is thrown when the data does not match the encoding
+// policy.
+type errInvalidEncoding struct {
+	message string
+}
+
+// newErrInvalidEncoding returns a new instance of the error.
+func newErrInvalidEncoding(message string) *errInvalidEncoding {
+	return &errInvalidEncoding{
+		message: message,
+	}
+}
+
+func (e *errInvalidEncoding) Error() string {
+	return e.message
+}
+
+// Unwrap returns ErrInvalidEncoding.
+func (e *errInvalidEncoding) Unwrap() error {
+	return ErrInvalidEncoding
+}
@@ -30,3 +30,20 @@ func TestErrFixtureDirectoryIsFile(t *testing.T) {
 	assert.Equal(t, message, err.Error())
 	assert.IsType(t, &errFixtureDirectoryIsFile{}, err)
 }
+
+func TestErrInvalidOption(t *testing.T) {
+	message := "invalid value for WithDiffContext(-1): context lines cannot be negative"
+	err := newErrInvalidOption("WithDiffContext", -1, "context lines cannot be negative")
+
+	assert.Equal(t, message, err.Error())
+	assert.Equal(t, "WithDiffContext", err.Option())
+	assert.IsType(t, &errInvalidOption{}, err)
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestErrInvalidEncoding(t *testing.T) {
+	err := newErrInvalidEncoding("actual data is not valid UTF-8")
+
+	assert.Equal(t, "actual data is not valid UTF-8", err.Error())
+	assert.ErrorIs(t, err, ErrInvalidEncoding)
+}
@@ -24,8 +24,10 @@ type Tester interface {
 }
 
 // DiffFn takes in an actual and expected and will return a diff string
-// representing the differences between the two.
-type DiffFn func(actual string, expected string) string
+// representing the differences between the two. goldenFile is the path of
+// the golden file being compared and opts are the diff options configured
+// on the tester.
+type DiffFn func(actual string, expected string, goldenFile string, opts DiffOptions) string
 
 // DiffEngine is used to enumerate the diff engine processors that are
 // available.
@@ -56,6 +58,63 @@ const (
 	// Expected: <data>
 	// Got: <data>
 	Simple
+
+	// SideBySide shows expected and actual in two columns sized to the
+	// width of the terminal, similar to `diff --side-by-side`.
+	//
+	//		Expected              Actual
+	//		--------              ------
+	//		Lorem dolor sit amet. | Lorem ipsum dolor.
+	SideBySide
+
+	// VisibleDiff produces a diff like ClassicDiff, but invisible characters
+	// like escape characters, carriage returns, tabs and trailing spaces are
+	// replaced with visible symbols. Useful for comparing shell output.
+	//
+	//		-␛[38;5;197m[ERROR] message·␛[0m
+	//		+␛[38;5;196m[ERROR] message·␛[0m
+	VisibleDiff
+)
+
+// valid returns true if the engine is a known diff engine.
+func (e DiffEngine) valid() bool {
+	return e > UndefinedDiff && e <= VisibleDiff
+}
+
+// LineEnding is used to enumerate the line ending policies applied to the
+// golden files and the actual data.
+type LineEnding int
+
+const (
+	// Preserve compares line endings byte for byte and writes them to the
+	// golden files as is.
+	Preserve LineEnding = iota
+
+	// LF converts CRLF line endings to LF on read, compare and update. This
+	// makes golden files immune to `core.autocrlf`.
+	LF
+
+	// CRLF converts all line endings to CRLF on read, compare and update.
+	CRLF
+)
+
+// Encoding is used to enumerate the encoding policies applied to the golden
+// files and the actual data.
+type Encoding int
+
+const (
+	// AnyEncoding treats data as raw bytes. No encoding checks are done.
+	AnyEncoding Encoding = iota
+
+	// UTF8 requires actual data and golden files to be valid UTF-8 without
+	// a byte order mark. Golden files with a byte order mark are reported as
+	// mismatches.
+	UTF8
+
+	// UTF8BOM requires actual data and golden files to be valid UTF-8.
+	// Golden files are written with a byte order mark and must start with
+	// one, which is otherwise ignored while comparing.
+	UTF8BOM
 )
 
 // OptionProcessor defines the functions that can be called to set values for
@@ -69,9 +128,16 @@ type OptionProcessor interface {
 
 	WithDiffEngine(engine DiffEngine) error
 	WithDiffFn(fn DiffFn) error
+	WithDiffContext(lines int) error
+	WithDiffMaxLines(lines int) error
+	WithDiffWidth(width int) error
 	WithIgnoreTemplateErrors(ignoreErrors bool) error
 	WithTestNameForDir(use bool) error
 	WithSubTestNameForDir(use bool) error
+	WithNormalizeANSI(normalize bool) error
+	WithLineEndings(policy LineEnding) error
+	WithEncoding(encoding Encoding) error
+	WithPlatformVariants(use bool) error
 }
 
 // === OptionProcessor ===============================
@@ -133,6 +199,35 @@ func WithDiffFn(fn DiffFn) Option {
 	}
 }
 
+// WithDiffContext sets the number of unchanged lines shown around changes
+// by ClassicDiff and SideBySide engines.
+//
+// Defaults to 1.
+func WithDiffContext(lines int) Option {
+	return func(o OptionProcessor) error {
+		return o.WithDiffContext(lines)
+	}
+}
+
+// WithDiffMaxLines limits the number of lines of diff output. Remaining lines
+// are replaced with a "… N more lines" marker.
+//
+// Defaults to 0 (unlimited).
+func WithDiffMaxLines(lines int) Option {
+	return func(o OptionProcessor) error {
+		return o.WithDiffMaxLines(lines)
+	}
+}
+
+// WithDiffWidth sets the width of SideBySide diff output.
+//
+// Defaults to 0 (width of the terminal).
+func WithDiffWidth(width int) Option {
+	return func(o OptionProcessor) error {
+		return o.WithDiffWidth(width)
+	}
+}
+
 // WithIgnoreTemplateErrors allows template processing to ignore any variables
 // in the template that do not have corresponding data values passed in.
 //
@@ -163,3 +258,46 @@ func WithSubTestNameForDir(use bool) Option {
 		return o.WithSubTestNameForDir(use)
 	}
 }
+
+// WithNormalizeANSI normalizes ANSI SGR escape sequences in both actual and
+// golden data before comparing, so that equivalent sequences like `\e[m`
+// and `\e[0m` are not reported as mismatches.
+//
+// Default value is false.
+func WithNormalizeANSI(normalize bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithNormalizeANSI(normalize)
+	}
+}
+
+// WithLineEndings sets the line ending policy applied consistently while
+// reading golden files, comparing and updating them.
+//
+// Default value is Preserve.
+func WithLineEndings(policy LineEnding) Option {
+	return func(o OptionProcessor) error {
+		return o.WithLineEndings(policy)
+	}
+}
+
+// WithEncoding sets the encoding policy applied consistently while reading
+// golden files, comparing and updating them.
+//
+// Default value is AnyEncoding.
+func WithEncoding(encoding Encoding) Option {
+	return func(o OptionProcessor) error {
+		return o.WithEncoding(encoding)
+	}
+}
+
+// WithPlatformVariants enables platform specific golden files named like
+// `name.linux-arm64.golden.txt`, falling back to `name.linux.golden.txt` and
+// then to the generic `name.golden.txt`. Updating only writes the golden file
+// of the current platform.
+//
+// Default value is false.
+func WithPlatformVariants(use bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithPlatformVariants(use)
+	}
+}
@@ -1,11 +1,18 @@
 package apollo
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
 
 // WithFixtureDir sets the fixture directory.
 //
 // Defaults to `testdata`
 func (a *Apollo) WithFixtureDir(dir string) error {
+	if dir == "" {
+		return newErrInvalidOption("WithFixtureDir", dir, "fixture directory cannot be empty")
+	}
 	a.fixtureDir = dir
 	return nil
 }
@@ -14,6 +21,9 @@ func (a *Apollo) WithFixtureDir(dir string) error {
 //
 // Defaults to `.golden.txt`
 func (a *Apollo) WithNameSuffix(suffix string) error {
+	if filepath.IsAbs(suffix) {
+		return newErrInvalidOption("WithNameSuffix", suffix, "suffix cannot be an absolute path")
+	}
 	a.fileNameSuffix = suffix
 	return nil
 }
@@ -23,6 +33,12 @@ func (a *Apollo) WithNameSuffix(suffix string) error {
 //
 // Defaults to 0644.
 func (a *Apollo) WithFilePerms(mode os.FileMode) error {
+	if mode&^os.ModePerm != 0 {
+		return newErrInvalidOption("WithFilePerms", fmt.Sprintf("%#o", mode), "only permission bits are allowed")
+	}
+	if mode&0600 != 0600 {
+		return newErrInvalidOption("WithFilePerms", fmt.Sprintf("%#o", mode), "golden files must be readable and writable by owner")
+	}
 	a.filePerms = mode
 	return nil
 }
@@ -32,6 +48,12 @@ func (a *Apollo) WithFilePerms(mode os.FileMode) error {
 //
 // Defaults to 0755.
 func (a *Apollo) WithDirPerms(mode os.FileMode) error {
+	if mode&^os.ModePerm != 0 {
+		return newErrInvalidOption("WithDirPerms", fmt.Sprintf("%#o", mode), "only permission bits are allowed")
+	}
+	if mode&0700 != 0700 {
+		return newErrInvalidOption("WithDirPerms", fmt.Sprintf("%#o", mode), "fixture directories must be accessible by owner")
+	}
 	a.dirPerms = mode
 	return nil
 }
@@ -39,6 +61,9 @@ func (a *Apollo) WithDirPerms(mode os.FileMode) error {
 // WithDiffEngine sets the `diff` engine that will be used to generate the
 // `diff` text.
 func (a *Apollo) WithDiffEngine(engine DiffEngine) error {
+	if !engine.valid() {
+		return newErrInvalidOption("WithDiffEngine", engine, "unknown diff engine")
+	}
 	a.diffEngine = engine
 	return nil
 }
@@ -47,10 +72,48 @@ func (a *Apollo) WithDiffEngine(engine DiffEngine) error {
 // DiffFn signature. This allows for any customized diff logic you would like
 // to create.
 func (a *Apollo) WithDiffFn(fn DiffFn) error {
+	if fn == nil {
+		return newErrInvalidOption("WithDiffFn", fn, "diff function cannot be nil")
+	}
 	a.diffFn = fn
 	return nil
 }
 
+// WithDiffContext sets the number of unchanged lines shown around changes
+// by ClassicDiff and SideBySide engines.
+//
+// Defaults to 1.
+func (a *Apollo) WithDiffContext(lines int) error {
+	if lines < 0 {
+		return newErrInvalidOption("WithDiffContext", lines, "context lines cannot be negative")
+	}
+	a.diffOptions.Context = lines
+	return nil
+}
+
+// WithDiffMaxLines limits the number of lines of diff output. Remaining lines
+// are replaced with a "… N more lines" marker.
+//
+// Defaults to 0 (unlimited).
+func (a *Apollo) WithDiffMaxLines(lines int) error {
+	if lines < 0 {
+		return newErrInvalidOption("WithDiffMaxLines", lines, "max lines cannot be negative")
+	}
+	a.diffOptions.MaxLines = lines
+	return nil
+}
+
+// WithDiffWidth sets the width of SideBySide diff output.
+//
+// Defaults to 0 (width of the terminal).
+func (a *Apollo) WithDiffWidth(width int) error {
+	if width < 0 {
+		return newErrInvalidOption("WithDiffWidth", width, "width cannot be negative")
+	}
+	a.diffOptions.Width = width
+	return nil
+}
+
 // WithIgnoreTemplateErrors allows template processing to ignore any variables
 // in the template that do not have corresponding data values passed in.
 //
@@ -78,3 +141,52 @@ func (a *Apollo) WithSubTestNameForDir(use bool) error {
 	a.useSubTestNameForDir = use
 	return nil
 }
+
+// WithNormalizeANSI normalizes ANSI SGR escape sequences in both actual and
+// golden data before comparing, so that equivalent sequences like `\e[m`
+// and `\e[0m` are not reported as mismatches.
+//
+// Default value is false.
+func (a *Apollo) WithNormalizeANSI(normalize bool) error {
+	a.normalizeANSI = normalize
+	return nil
+}
+
+// WithLineEndings sets the line ending policy applied consistently while
+// reading golden files, comparing and updating them.
+//
+// Default value is Preserve.
+func (a *Apollo) WithLineEndings(policy LineEnding) error {
+	switch policy {
+	case Preserve, LF, CRLF:
+		a.lineEndings = policy
+		return nil
+	default:
+		return newErrInvalidOption("WithLineEndings", policy, "unknown line ending policy")
+	}
+}
+
+// WithEncoding sets the encoding policy applied consistently while reading
+// golden files, comparing and updating them.
+//
+// Default value is AnyEncoding.
+func (a *Apollo) WithEncoding(encoding Encoding) error {
+	switch encoding {
+	case AnyEncoding, UTF8, UTF8BOM:
+		a.encoding = encoding
+		return nil
+	default:
+		return newErrInvalidOption("WithEncoding", encoding, "unknown encoding")
+	}
+}
+
+// WithPlatformVariants enables platform specific golden files named like
+// `name.linux-arm64.golden.txt`, falling back to `name.linux.golden.txt` and
+// then to the generic `name.golden.txt`. Updating only writes the golden file
+// of the current platform.
+//
+// Default value is false.
+func (a *Apollo) WithPlatformVariants(use bool) error {
+	a.usePlatformVariants = use
+	return nil
+}
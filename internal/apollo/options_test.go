@@ -0,0 +1,91 @@
+package apollo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionValidation(t *testing.T) {
+	tests := map[string]struct {
+		option Option
+		valid  bool
+	}{
+		"fixture dir":                 {option: WithFixtureDir("fixtures"), valid: true},
+		"empty fixture dir":           {option: WithFixtureDir("")},
+		"relative suffix":             {option: WithNameSuffix(".golden.json"), valid: true},
+		"empty suffix":                {option: WithNameSuffix(""), valid: true},
+		"absolute suffix":             {option: WithNameSuffix("/tmp/golden.txt")},
+		"file perms":                  {option: WithFilePerms(0600), valid: true},
+		"zero file perms":             {option: WithFilePerms(0)},
+		"read only file perms":        {option: WithFilePerms(0444)},
+		"file perms with type bits":   {option: WithFilePerms(0644 | 1<<31)},
+		"dir perms":                   {option: WithDirPerms(0700), valid: true},
+		"zero dir perms":              {option: WithDirPerms(0)},
+		"dir perms without exec":      {option: WithDirPerms(0644)},
+		"classic diff":                {option: WithDiffEngine(ClassicDiff), valid: true},
+		"visible diff":                {option: WithDiffEngine(VisibleDiff), valid: true},
+		"undefined diff":              {option: WithDiffEngine(UndefinedDiff)},
+		"unknown diff":                {option: WithDiffEngine(DiffEngine(100))},
+		"nil diff fn":                 {option: WithDiffFn(nil)},
+		"diff context":                {option: WithDiffContext(0), valid: true},
+		"negative diff context":       {option: WithDiffContext(-1)},
+		"diff max lines":              {option: WithDiffMaxLines(10), valid: true},
+		"negative diff max lines":     {option: WithDiffMaxLines(-1)},
+		"diff width":                  {option: WithDiffWidth(80), valid: true},
+		"negative diff width":         {option: WithDiffWidth(-80)},
+		"line endings":                {option: WithLineEndings(CRLF), valid: true},
+		"unknown line endings":        {option: WithLineEndings(LineEnding(10))},
+		"encoding":                    {option: WithEncoding(UTF8), valid: true},
+		"unknown encoding":            {option: WithEncoding(Encoding(-1))},
+		"platform variants":           {option: WithPlatformVariants(true), valid: true},
+		"preset":                      {option: ShellOutputPreset, valid: true},
+		"preset with invalid options": {option: Preset(WithFixtureDir("fixtures"), WithFixtureDir(""))},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := Apollo{}
+			err := test.option(&a)
+			if test.valid {
+				assert.Nil(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidOption)
+			}
+		})
+	}
+}
+
+func TestPresetOrder(t *testing.T) {
+	a := New(t, ShellOutputPreset, WithTestNameForDir(false), WithDiffMaxLines(5))
+
+	assert.Equal(t, VisibleDiff, a.diffEngine)
+	assert.True(t, a.normalizeANSI)
+	assert.False(t, a.useTestNameForDir)
+	assert.Equal(t, 5, a.diffOptions.MaxLines)
+}
+
+func TestWith(t *testing.T) {
+	parent := New(t, WithFixtureDir("fixtures"), WithDiffEngine(Simple))
+	child, err := parent.With(WithDiffEngine(SideBySide), WithNameSuffix(".txt"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "fixtures", child.fixtureDir)
+	assert.Equal(t, SideBySide, child.diffEngine)
+	assert.Equal(t, ".txt", child.fileNameSuffix)
+
+	// parent is not modified
+	assert.Equal(t, Simple, parent.diffEngine)
+	assert.Equal(t, defaultFileNameSuffix, parent.fileNameSuffix)
+}
+
+func TestWithInvalid(t *testing.T) {
+	parent := New(t, WithDiffEngine(Simple))
+	child, err := parent.With(WithDiffEngine(SideBySide), WithFilePerms(0))
+
+	assert.Nil(t, child)
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	// parent is not modified, even by the options applied before the error.
+	assert.Equal(t, Simple, parent.diffEngine)
+}
@@ -0,0 +1,28 @@
+package apollo
+
+// Preset bundles multiple options into a single Option. Options are applied
+// in order, so options given after a preset override the values set by the
+// preset. Presets can be nested.
+//
+//	g := apollo.New(t, apollo.ShellOutputPreset, apollo.WithDiffMaxLines(50))
+func Preset(options ...Option) Option {
+	return func(o OptionProcessor) error {
+		for _, option := range options {
+			if err := option(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// ShellOutputPreset is suitable for comparing output of shell scripts. It uses
+// VisibleDiff engine, so that escape sequences and trailing spaces are
+// visible in diffs, normalizes ANSI escape sequences and LF line endings and
+// stores golden files in a directory named after the test.
+var ShellOutputPreset = Preset(
+	WithDiffEngine(VisibleDiff),
+	WithNormalizeANSI(true),
+	WithLineEndings(LF),
+	WithTestNameForDir(true),
+)
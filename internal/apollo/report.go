@@ -0,0 +1,302 @@
+package apollo
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// defaultReport collects all the fixture mismatches of the test run, when
+// `-report` flag is set.
+var defaultReport = NewReport()
+
+// ReportEntry is a single golden fixture mismatch.
+type ReportEntry struct {
+	Test       string
+	GoldenFile string
+	Expected   string
+	Actual     string
+}
+
+// Report aggregates golden fixture mismatches of a test run, so that they
+// can be rendered as a single HTML or Markdown document instead of reading
+// them from `go test` output. It is safe for concurrent use.
+type Report struct {
+	mu      sync.Mutex
+	entries []ReportEntry
+}
+
+// NewReport returns a new empty Report.
+func NewReport() *Report {
+	return &Report{}
+}
+
+// Add adds a mismatch to the report.
+func (r *Report) Add(entry ReportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Len returns number of mismatches recorded.
+func (r *Report) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// reportGroup is a list of mismatches for a single golden file.
+type reportGroup struct {
+	GoldenFile string
+	Entries    []ReportEntry
+}
+
+// groups returns recorded mismatches grouped by golden file and sorted by
+// golden file name and test name.
+func (r *Report) groups() []reportGroup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := make(map[string]int)
+	var groups []reportGroup
+	for _, e := range r.entries {
+		i, ok := index[e.GoldenFile]
+		if !ok {
+			i = len(groups)
+			index[e.GoldenFile] = i
+			groups = append(groups, reportGroup{GoldenFile: e.GoldenFile})
+		}
+		groups[i].Entries = append(groups[i].Entries, e)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].GoldenFile < groups[j].GoldenFile
+	})
+	for _, g := range groups {
+		sort.SliceStable(g.Entries, func(i, j int) bool {
+			return g.Entries[i].Test < g.Entries[j].Test
+		})
+	}
+	return groups
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ansi":  func(s string) template.HTML { return template.HTML(ansiToHTML(s)) },
+	"rows":  sideBySideRows,
+	"class": func(op byte) string { return string(op) },
+	"lineno": func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return fmt.Sprint(n)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Golden fixture mismatches</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; table-layout: fixed; }
+td, th { font-family: monospace; white-space: pre-wrap; word-break: break-all; vertical-align: top; padding: 0 0.5em; }
+th { text-align: left; background: #eee; }
+td.no { width: 3em; color: #888; text-align: right; user-select: none; }
+tr.r td.text, tr.d td.text, tr.i td.text { background: #fff5b1; }
+tr.d td.expected, tr.r td.expected { background: #ffdce0; }
+tr.i td.actual, tr.r td.actual { background: #dcffe4; }
+</style>
+</head>
+<body>
+<h1>Golden fixture mismatches</h1>
+<p>{{ len . }} golden file(s) with mismatches.</p>
+{{- range . }}
+<h2>{{ .GoldenFile }}</h2>
+{{- range .Entries }}
+<h3>{{ .Test }}</h3>
+<table>
+<tr><th class="no"></th><th>Expected</th><th class="no"></th><th>Actual</th></tr>
+{{- range rows .Expected .Actual }}
+<tr class="{{ class .Op }}"><td class="no">{{ lineno .ExpectedNo }}</td><td class="text expected">{{ ansi .Expected }}</td><td class="no">{{ lineno .ActualNo }}</td><td class="text actual">{{ ansi .Actual }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// WriteHTML writes a self contained HTML report with side by side diffs
+// grouped by golden file. ANSI escape sequences are rendered as colors.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportHTMLTemplate.Execute(w, r.groups())
+}
+
+// WriteMarkdown writes a Markdown report suitable for CI job summaries.
+// Mismatches are grouped by golden file and ANSI escape characters are made
+// visible, as Markdown cannot render colors.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	groups := r.groups()
+
+	var b strings.Builder
+	b.WriteString("# Golden fixture mismatches\n\n")
+	fmt.Fprintf(&b, "%d golden file(s) with mismatches.\n", len(groups))
+
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\n## `%s`\n", g.GoldenFile)
+		for _, e := range g.Entries {
+			fmt.Fprintf(&b, "\n<details>\n<summary><code>%s</code></summary>\n\n", template.HTMLEscapeString(e.Test))
+			b.WriteString("```diff\n")
+			diff := Diff(ClassicDiff, ansiToVisible(e.Actual), ansiToVisible(e.Expected))
+			b.WriteString(diff)
+			if !strings.HasSuffix(diff, "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString("```\n\n</details>\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteFile writes the report to the given file. If the file has `.md` or
+// `.markdown` extension, Markdown report is written, otherwise HTML.
+func (r *Report) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		err = r.WriteMarkdown(f)
+	default:
+		err = r.WriteHTML(f)
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// reportRun is the state of a report shared by the test binaries of a
+// single `go test` invocation, saved next to the report file.
+type reportRun struct {
+	// Run identifies the go test invocation, by the pid of the go command
+	// which started the test binaries.
+	Run     int           `json:"run"`
+	Entries []ReportEntry `json:"entries"`
+}
+
+// reportLockTimeout is how long MergeFile waits for other test binaries
+// writing the same report.
+const reportLockTimeout = time.Minute
+
+// MergeFile writes the report to the given file like WriteFile, along with
+// the mismatches recorded by other test binaries of the same `go test`
+// invocation, as with `go test ./...` every package is built into its own
+// test binary. Mismatches are saved to `<name>.json` for this purpose,
+// which is overwritten by the next invocation. Relative golden file names
+// are made relative to the directory of the report, as they would be
+// ambiguous otherwise.
+func (r *Report) MergeFile(name string) error {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(name+".lock", reportLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	run := reportRun{Run: os.Getppid()}
+	if data, err := ioutil.ReadFile(name + ".json"); err == nil {
+		var saved reportRun
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return fmt.Errorf("invalid report state %s: %w", name+".json", err)
+		}
+		if saved.Run == run.Run {
+			run.Entries = saved.Entries
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	r.mu.Lock()
+	for _, e := range r.entries {
+		if !filepath.IsAbs(e.GoldenFile) {
+			if abs, err := filepath.Abs(e.GoldenFile); err == nil {
+				if rel, err := filepath.Rel(filepath.Dir(name), abs); err == nil {
+					e.GoldenFile = filepath.ToSlash(rel)
+				}
+			}
+		}
+		run.Entries = append(run.Entries, e)
+	}
+	r.mu.Unlock()
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(name+".json", data, 0644); err != nil {
+		return err
+	}
+	return (&Report{entries: run.Entries}).WriteFile(name)
+}
+
+// lockFile creates the lock file, waiting for it to be removed if it
+// exists. Returned function removes it.
+func lockFile(name string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Run runs the tests and writes the report of all the golden fixture
+// mismatches at the end of the run if `-report` flag is set. Path of the
+// report is relative to the package directory, thus an absolute path must be
+// used to get a single report of all the packages with `go test ./...`,
+// see MergeFile. It should be called from TestMain.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(apollo.Run(m))
+//	}
+func Run(m *testing.M) int {
+	code := m.Run()
+
+	if *reportFile != "" {
+		if err := defaultReport.MergeFile(*reportFile); err != nil {
+			fmt.Fprintf(os.Stderr, "apollo: failed to write report: %s\n", err)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,124 @@
+package apollo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportGroups(t *testing.T) {
+	r := NewReport()
+	r.Add(ReportEntry{Test: "TestB", GoldenFile: "testdata/b.golden.txt"})
+	r.Add(ReportEntry{Test: "TestA/2", GoldenFile: "testdata/a.golden.txt"})
+	r.Add(ReportEntry{Test: "TestA/1", GoldenFile: "testdata/a.golden.txt"})
+
+	groups := r.groups()
+	require.Len(t, groups, 2)
+	assert.Equal(t, 3, r.Len())
+	assert.Equal(t, "testdata/a.golden.txt", groups[0].GoldenFile)
+	assert.Equal(t, "TestA/1", groups[0].Entries[0].Test)
+	assert.Equal(t, "TestA/2", groups[0].Entries[1].Test)
+	assert.Equal(t, "testdata/b.golden.txt", groups[1].GoldenFile)
+}
+
+func TestReportWriters(t *testing.T) {
+	r := NewReport()
+	r.Add(ReportEntry{
+		Test:       "TestLogger/bash",
+		GoldenFile: "testdata/full-colored-40.golden.txt",
+		Expected:   "\x1b[38;5;197m[ERROR] <one>\x1b[0m\n",
+		Actual:     "\x1b[38;5;196m[ERROR] <one>\x1b[0m\n",
+	})
+
+	t.Run("html", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, r.WriteHTML(&b))
+		out := b.String()
+		assert.Contains(t, out, "<h2>testdata/full-colored-40.golden.txt</h2>")
+		assert.Contains(t, out, "<h3>TestLogger/bash</h3>")
+		assert.Contains(t, out, `<span style="color:#ff005f;">[ERROR] &lt;one&gt;</span>`)
+		assert.Contains(t, out, `<span style="color:#ff0000;">[ERROR] &lt;one&gt;</span>`)
+		assert.NotContains(t, out, "\x1b")
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, r.WriteMarkdown(&b))
+		out := b.String()
+		assert.Contains(t, out, "## `testdata/full-colored-40.golden.txt`")
+		assert.Contains(t, out, "<summary><code>TestLogger/bash</code></summary>")
+		assert.Contains(t, out, "-␛[38;5;197m[ERROR] <one>␛[0m")
+		assert.Contains(t, out, "+␛[38;5;196m[ERROR] <one>␛[0m")
+		assert.NotContains(t, out, "\x1b")
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"report.md", "report.html"} {
+			require.NoError(t, r.WriteFile(filepath.Join(dir, name)))
+			_, err := os.Stat(filepath.Join(dir, name))
+			assert.Nil(t, err)
+		}
+	})
+}
+
+func TestReportMergeFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "report.md")
+
+	// state of a previous go test invocation is discarded.
+	stale, err := json.Marshal(reportRun{Run: -1, Entries: []ReportEntry{{Test: "TestStale", GoldenFile: "stale.golden"}}})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(name+".json", stale, 0644))
+
+	first := NewReport()
+	first.Add(ReportEntry{Test: "TestFirst", GoldenFile: filepath.Join(dir, "a", "first.golden"), Expected: "a", Actual: "b"})
+	require.NoError(t, first.MergeFile(name))
+
+	second := NewReport()
+	second.Add(ReportEntry{Test: "TestSecond", GoldenFile: "testdata/second.golden", Expected: "c", Actual: "d"})
+	require.NoError(t, second.MergeFile(name))
+
+	data, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "2 golden file(s) with mismatches.")
+	assert.Contains(t, out, "<code>TestFirst</code>")
+	assert.Contains(t, out, "<code>TestSecond</code>")
+	assert.NotContains(t, out, "TestStale")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	rel, err := filepath.Rel(dir, filepath.Join(wd, "testdata", "second.golden"))
+	require.NoError(t, err)
+	assert.Contains(t, out, "## `"+filepath.ToSlash(rel)+"`", "relative to report")
+
+	_, err = os.Stat(name + ".lock")
+	assert.True(t, os.IsNotExist(err), "lock is removed")
+}
+
+func TestCompareRecordsReport(t *testing.T) {
+	a := New(t)
+	a.report = NewReport()
+
+	err := a.Update(t, "report", []byte("abc"))
+	require.NoError(t, err)
+
+	assert.Nil(t, a.compare(t, "report", []byte("abc")))
+	assert.Equal(t, 0, a.report.Len())
+
+	assert.IsType(t, &errFixtureMismatch{}, a.compare(t, "report", []byte("abd")))
+	require.Equal(t, 1, a.report.Len())
+	assert.Equal(t, a.GoldenFileName(t, "report"), a.report.entries[0].GoldenFile)
+	assert.Equal(t, "abc", a.report.entries[0].Expected)
+	assert.Equal(t, "abd", a.report.entries[0].Actual)
+
+	err = os.RemoveAll(a.fixtureDir)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package apollo
+
+import "os"
+
+// fileTerminalWidth is not supported on this platform and always returns
+// zero.
+func fileTerminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package apollo
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// fileTerminalWidth returns number of columns of the terminal f refers to,
+// or zero if f is not a terminal.
+func fileTerminalWidth(f *os.File) int {
+	var ws struct {
+		Row, Col, Xpixel, Ypixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+	return int(ws.Col)
+}
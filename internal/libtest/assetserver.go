@@ -0,0 +1,241 @@
+package libtest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// AssetBehavior is the scripted behavior of a single request to a route of
+// the AssetServer. Zero value serves the file from the asset directory.
+type AssetBehavior struct {
+	// Status is the HTTP status code of the response. Error responses have an
+	// empty body. Defaults to 200.
+	Status int
+	// Location redirects the request to the URL with status 302, unless
+	// Status is set to another redirect status code. Location can point to
+	// other hosts, like AltURL or another AssetServer.
+	Location string
+	// Delay delays the response, to simulate slow servers.
+	Delay time.Duration
+	// Body overrides the file contents served.
+	Body []byte
+	// Empty serves an empty body with status 200.
+	Empty bool
+	// Truncate sends only first half of the body while advertising the full
+	// Content-Length and closes the connection.
+	Truncate bool
+}
+
+// AssetRequest is a request recorded by the AssetServer.
+type AssetRequest struct {
+	Method string
+	// Host is the host header of the request, which includes the port.
+	Host   string
+	Path   string
+	Header http.Header
+}
+
+// assetRoute is a route with behaviors.
+type assetRoute struct {
+	behaviors []AssetBehavior
+	hits      int
+}
+
+// AssetServer is a local HTTP server, which serves files from a directory,
+// for end-to-end download tests without network access. Routes can be
+// scripted to return errors, redirects, slow, truncated or empty responses.
+// All requests are recorded, including their headers.
+type AssetServer struct {
+	t      *testing.T
+	dir    string
+	server *httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]*assetRoute
+	requests []AssetRequest
+}
+
+// NewAssetServer starts a new AssetServer serving files from testdata
+// directory of the package. Server is closed when the test completes.
+func NewAssetServer(t *testing.T) *AssetServer {
+	t.Helper()
+	return NewAssetServerWithDir(t, "testdata")
+}
+
+// NewAssetServerWithDir starts a new AssetServer serving files from the
+// directory. Server is closed when the test completes.
+func NewAssetServerWithDir(t *testing.T, dir string) *AssetServer {
+	t.Helper()
+
+	s := &AssetServer{
+		t:      t,
+		dir:    dir,
+		routes: make(map[string]*assetRoute),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// Handle sets the behaviors of the route. Nth request to the route uses Nth
+// behavior, and the last behavior is used once all behaviors are exhausted.
+// This allows expressing things like "500 then success" for retry tests.
+//
+//	s.Handle("/checksum.txt", libtest.AssetBehavior{Status: 500}, libtest.AssetBehavior{})
+func (s *AssetServer) Handle(path string, behaviors ...AssetBehavior) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = &assetRoute{behaviors: behaviors}
+}
+
+// URL returns the URL of the path on the server.
+func (s *AssetServer) URL(path string) string {
+	return s.server.URL + path
+}
+
+// AltURL returns the URL of the path on the server using "localhost" as
+// host name instead of the IP address. Clients treat it as a different host,
+// so it can be used to test cross host redirects.
+func (s *AssetServer) AltURL(path string) string {
+	u, err := url.Parse(s.server.URL)
+	if err != nil {
+		s.t.Fatalf("invalid server URL %s: %s", s.server.URL, err)
+	}
+	_, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		s.t.Fatalf("invalid server URL %s: %s", s.server.URL, err)
+	}
+	u.Host = net.JoinHostPort("localhost", port)
+	return u.String() + path
+}
+
+// Requests returns all the requests received by the server, in order.
+func (s *AssetServer) Requests() []AssetRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AssetRequest(nil), s.requests...)
+}
+
+// RequestsFor returns the requests received for the path, in order.
+func (s *AssetServer) RequestsFor(path string) []AssetRequest {
+	var requests []AssetRequest
+	for _, r := range s.Requests() {
+		if r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// behavior records the request and returns the behavior for it.
+func (s *AssetServer) behavior(r *http.Request) AssetBehavior {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, AssetRequest{
+		Method: r.Method,
+		Host:   r.Host,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+	})
+
+	route, ok := s.routes[r.URL.Path]
+	if !ok || len(route.behaviors) == 0 {
+		return AssetBehavior{}
+	}
+
+	i := route.hits
+	if i >= len(route.behaviors) {
+		i = len(route.behaviors) - 1
+	}
+	route.hits++
+	return route.behaviors[i]
+}
+
+func (s *AssetServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b := s.behavior(r)
+
+	if b.Delay > 0 {
+		select {
+		case <-time.After(b.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if b.Location != "" {
+		status := b.Status
+		if status == 0 {
+			status = http.StatusFound
+		}
+		http.Redirect(w, r, b.Location, status)
+		return
+	}
+
+	if b.Status != 0 && b.Status != http.StatusOK {
+		w.WriteHeader(b.Status)
+		return
+	}
+
+	if b.Empty {
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body := b.Body
+	if body == nil {
+		var err error
+		body, err = s.readFile(r.URL.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			http.NotFound(w, r)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if b.Truncate {
+		// Server closes the connection, as handler wrote less than
+		// advertised Content-Length.
+		w.Write(body[:len(body)/2])
+		return
+	}
+	w.Write(body)
+}
+
+// readFile reads the file for the URL path from the asset directory.
+func (s *AssetServer) readFile(path string) ([]byte, error) {
+	name := filepath.Join(s.dir, filepath.FromSlash(filepath.Clean("/"+path)))
+	if !strings.HasPrefix(name, filepath.Clean(s.dir)) {
+		return nil, os.ErrNotExist
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.ReadFile(name)
+}
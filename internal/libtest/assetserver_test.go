@@ -0,0 +1,78 @@
+package libtest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetServer(t *testing.T) {
+	s := NewAssetServer(t)
+	other := NewAssetServer(t)
+
+	s.Handle("/flaky.txt", AssetBehavior{Status: 500}, AssetBehavior{Body: []byte("ok")})
+	s.Handle("/redirect", AssetBehavior{Location: other.AltURL("/asset.txt")})
+	s.Handle("/empty", AssetBehavior{Empty: true})
+	s.Handle("/slow", AssetBehavior{Delay: 50 * time.Millisecond, Body: []byte("slow")})
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{name: "file", url: s.URL("/asset.txt"), status: 200, body: "hello from asset server\n"},
+		{name: "alt-url", url: s.AltURL("/asset.txt"), status: 200, body: "hello from asset server\n"},
+		{name: "missing", url: s.URL("/missing.txt"), status: 404, body: "404 page not found\n"},
+		{name: "traversal", url: s.URL("/../assetserver.go"), status: 404, body: "404 page not found\n"},
+		{name: "flaky-first", url: s.URL("/flaky.txt"), status: 500},
+		{name: "flaky-second", url: s.URL("/flaky.txt"), status: 200, body: "ok"},
+		{name: "flaky-third", url: s.URL("/flaky.txt"), status: 200, body: "ok"},
+		{name: "redirect", url: s.URL("/redirect"), status: 200, body: "hello from asset server\n"},
+		{name: "empty", url: s.URL("/empty"), status: 200},
+		{name: "slow", url: s.URL("/slow"), status: 200, body: "slow"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "token "+tc.name)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode)
+			assert.Equal(t, tc.body, string(body))
+		})
+	}
+
+	assert.Len(t, s.Requests(), len(tests))
+	flaky := s.RequestsFor("/flaky.txt")
+	if assert.Len(t, flaky, 3) {
+		assert.Equal(t, "token flaky-first", flaky[0].Header.Get("Authorization"))
+	}
+	// Go http client drops Authorization header on cross host redirects.
+	if redirected := other.RequestsFor("/asset.txt"); assert.Len(t, redirected, 1) {
+		assert.Empty(t, redirected[0].Header.Get("Authorization"))
+	}
+}
+
+func TestAssetServerTruncate(t *testing.T) {
+	s := NewAssetServer(t)
+	s.Handle("/asset.txt", AssetBehavior{Truncate: true})
+
+	resp, err := http.Get(s.URL("/asset.txt"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Error(t, err)
+	assert.Equal(t, "hello from a", string(body))
+}
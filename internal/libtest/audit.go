@@ -0,0 +1,220 @@
+package libtest
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// FSAudit checks that shell invocations do not change the filesystem in
+// unexpected ways, like leaking temporary files or deleting files they
+// should not. It is enabled for an invocation with WithFSAudit.
+type FSAudit struct {
+	roots   []string
+	tmp     string
+	allowed []string
+}
+
+// FSChange is a change to the filesystem found by FSAudit.
+type FSChange struct {
+	Path string
+	// Kind is one of created, modified or deleted.
+	Kind string
+}
+
+// String returns the change in "kind path" form.
+func (c FSChange) String() string {
+	return c.Kind + " " + c.Path
+}
+
+// AuditFS returns an audit of TMPDIR, HOME and the working directory of the
+// shell, along with the given roots. TMPDIR of the shell is set to a new
+// directory, so that only temporary files of the shell are audited. Use
+// Allow for the intended outputs.
+//
+//	audit := libtest.AuditFS(t).Allow(output)
+//	libtest.Run(t, "bash", []string{"dl"}, script, libtest.WithFSAudit(audit))
+func AuditFS(t *testing.T, roots ...string) *FSAudit {
+	t.Helper()
+
+	a := &FSAudit{tmp: t.TempDir()}
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			t.Fatalf("invalid audit root %s: %s", root, err)
+		}
+		a.roots = append(a.roots, abs)
+	}
+	return a
+}
+
+// Allow allows changes to the paths matching the patterns, and to
+// everything below them. Patterns use filepath.Match syntax, relative
+// patterns are relative to the working directory of the shell.
+func (a *FSAudit) Allow(patterns ...string) *FSAudit {
+	a.allowed = append(a.allowed, patterns...)
+	return a
+}
+
+// TempDir returns the directory used as TMPDIR of the shell.
+func (a *FSAudit) TempDir() string {
+	return a.tmp
+}
+
+// WithFSAudit audits the filesystem before and after the shell invocation
+// and fails the test on changes which are not allowed.
+func WithFSAudit(a *FSAudit) RunOption {
+	return func(c *runConfig) {
+		c.audit = a
+		c.env = append(c.env, "TMPDIR="+a.tmp)
+	}
+}
+
+// fileState is the state of a path in a snapshot.
+type fileState struct {
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+	// link is the target of symlinks.
+	link string
+}
+
+// fsSnapshot maps paths to their states.
+type fsSnapshot map[string]fileState
+
+// auditRoots returns the directories to audit for the invocation.
+func (a *FSAudit) auditRoots(cfg runConfig) ([]string, string, error) {
+	dir := cfg.dir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, "", err
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	roots := []string{a.tmp, dir}
+	if cfg.hermetic != nil {
+		if home := cfg.hermetic.With(cfg.env...).Get("HOME"); home != "" {
+			roots = append(roots, home)
+		}
+	}
+	return append(roots, a.roots...), dir, nil
+}
+
+// snapshotFS records the state of the roots and everything below them.
+// Roots which do not exist are skipped.
+func snapshotFS(roots []string) (fsSnapshot, error) {
+	s := make(fsSnapshot)
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			state := fileState{mode: info.Mode()}
+			switch {
+			case info.Mode()&os.ModeSymlink != 0:
+				state.link, _ = os.Readlink(path)
+			case info.Mode().IsRegular():
+				state.size = info.Size()
+				state.modTime = info.ModTime()
+			}
+			s[path] = state
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// changes returns the changes between the snapshots which are not allowed.
+// Only the topmost path of created or deleted directories is reported.
+func (a *FSAudit) changes(before, after fsSnapshot, dir string) []FSChange {
+	var changes []FSChange
+	for path, state := range after {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			changes = append(changes, FSChange{Path: path, Kind: "created"})
+		case old != state:
+			changes = append(changes, FSChange{Path: path, Kind: "modified"})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, FSChange{Path: path, Kind: "deleted"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	// Parents sort before their children.
+	kinds := make(map[string]string)
+	var reported []FSChange
+	for _, c := range changes {
+		if a.allowedPath(c.Path, dir) {
+			continue
+		}
+		if c.Kind != "modified" {
+			kinds[c.Path] = c.Kind
+			if kinds[filepath.Dir(c.Path)] == c.Kind {
+				continue
+			}
+		}
+		reported = append(reported, c)
+	}
+	return reported
+}
+
+// allowedPath returns true if the path or any of its parents matches an
+// allowed pattern.
+func (a *FSAudit) allowedPath(path, dir string) bool {
+	for _, pattern := range a.allowed {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		for p := path; ; p = filepath.Dir(p) {
+			if ok, _ := filepath.Match(pattern, p); ok {
+				return true
+			}
+			if p == filepath.Dir(p) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// audit runs the invocation between snapshots of the audited directories
+// and fails the test on changes which are not allowed.
+func (a *FSAudit) audit(t *testing.T, cfg runConfig, invoke func()) {
+	t.Helper()
+
+	roots, dir, err := a.auditRoots(cfg)
+	if err != nil {
+		t.Fatalf("filesystem audit failed: %s", err)
+	}
+	before, err := snapshotFS(roots)
+	if err != nil {
+		t.Fatalf("filesystem audit failed: %s", err)
+	}
+	invoke()
+	after, err := snapshotFS(roots)
+	if err != nil {
+		t.Fatalf("filesystem audit failed: %s", err)
+	}
+	for _, c := range a.changes(before, after, dir) {
+		t.Errorf("filesystem audit: unexpected change: %s", c)
+	}
+}
@@ -0,0 +1,94 @@
+package libtest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditFS(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"keep.txt", "modify.txt", "delete.txt"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+	extra := t.TempDir()
+
+	a := AuditFS(t, extra).Allow("out.txt", "cache/*")
+	cfg := runConfig{dir: dir, hermetic: NewEnv(t)}
+	WithFSAudit(a)(&cfg)
+
+	roots, wd, err := a.auditRoots(cfg)
+	require.NoError(t, err)
+	before, err := snapshotFS(roots)
+	require.NoError(t, err)
+
+	r, err := run(t, "sh", nil, strings.Join([]string{
+		`mktemp -d >/dev/null`,
+		`mkdir -p "$HOME/cache/nested"`,
+		`touch "$1/leak"`,
+		`echo out > out.txt`,
+		`mkdir -p cache/a/b && echo > cache/a/b/c`,
+		`echo more >> modify.txt`,
+		`rm delete.txt`,
+	}, " && "), withArgs(cfg, extra))
+	require.NoError(t, err)
+	require.Equal(t, 0, r.ExitCode, r.Stderr)
+
+	after, err := snapshotFS(roots)
+	require.NoError(t, err)
+
+	var got []string
+	for _, c := range a.changes(before, after, wd) {
+		got = append(got, c.String())
+	}
+	var tmp []string
+	for i := 0; i < len(got); i++ {
+		if strings.HasPrefix(got[i], "created "+filepath.Join(a.TempDir(), "tmp.")) {
+			tmp = append(tmp, got[i])
+			got = append(got[:i], got[i+1:]...)
+			i--
+		}
+	}
+	assert.Len(t, tmp, 1, "temporary directory of mktemp must be reported")
+	assert.Equal(t, []string{
+		"created " + filepath.Join(dir, "cache"),
+		"deleted " + filepath.Join(dir, "delete.txt"),
+		"modified " + filepath.Join(dir, "modify.txt"),
+		"created " + filepath.Join(extra, "leak"),
+		"created " + filepath.Join(cfg.hermetic.Get("HOME"), "cache"),
+	}, got)
+}
+
+func TestAuditFSRun(t *testing.T) {
+	dir := t.TempDir()
+	a := AuditFS(t).Allow("out.txt")
+	r := Run(t, "sh", nil, `tmp="$(mktemp -d)" && echo out > out.txt && rm -rf "${tmp}"`,
+		WithDir(dir),
+		WithFSAudit(a),
+	)
+	assert.Equal(t, 0, r.ExitCode, r.Stderr)
+}
+
+func TestAuditFSDeletedRoot(t *testing.T) {
+	a := AuditFS(t)
+	before, err := snapshotFS([]string{a.TempDir()})
+	require.NoError(t, err)
+	_, err = run(t, "sh", nil, `rm -rf "$1"`, runConfig{args: []string{a.TempDir()}})
+	require.NoError(t, err)
+	after, err := snapshotFS([]string{a.TempDir()})
+	require.NoError(t, err)
+
+	changes := a.changes(before, after, "/")
+	require.Len(t, changes, 1)
+	assert.Equal(t, FSChange{Path: a.TempDir(), Kind: "deleted"}, changes[0])
+}
+
+// withArgs returns a copy of the config with the arguments.
+func withArgs(cfg runConfig, args ...string) runConfig {
+	cfg.args = args
+	return cfg
+}
@@ -0,0 +1,230 @@
+package libtest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"hash"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// checksumHashes are the hash algorithms fixtures can be generated for.
+var checksumHashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha224": sha256.New224,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// ChecksumFiles are the checksum files of a ChecksumFixture. Paths are
+// relative to the directory the generator was run from.
+type ChecksumFiles struct {
+	// Valid is a checksum file with a single entry for the target.
+	Valid string
+	// Invalid is a checksum file whose entry for the target is not a hash.
+	Invalid string
+	// Missing is a checksum file without an entry for the target.
+	Missing string
+	// Mismatch is a checksum file whose entry for the target is a valid hash
+	// of some other data.
+	Mismatch string
+	// CRLF is Valid with CRLF line endings.
+	CRLF string
+	// BSD is a checksum file in BSD style, `SHA256 (file) = hash`, as
+	// produced by `shasum --tag` and BSD checksum tools.
+	BSD string
+	// Multi is a checksum file with the target entry among entries of other
+	// files.
+	Multi string
+	// MultiPrefix is a checksum file with entries of other files whose names
+	// start with the name of target, like signatures, before the target entry.
+	MultiPrefix string
+	// Hash is a file containing only the hash, without a trailing newline.
+	Hash string
+	// HashLF is Hash with a trailing newline.
+	HashLF string
+	// HashLFLF is Hash with two trailing newlines.
+	HashLFLF string
+	// HashCR is Hash with a trailing carriage return.
+	HashCR string
+	// HashCRLF is Hash with a trailing CRLF.
+	HashCRLF string
+	// HashInvalid is a file containing only an invalid hash.
+	HashInvalid string
+	// HashMismatch is a file containing only the Mismatch hash.
+	HashMismatch string
+}
+
+// ChecksumFixture is a set of checksum fixtures of a target file for a hash
+// algorithm.
+type ChecksumFixture struct {
+	// Algorithm is the name of the hash algorithm, like "sha256".
+	Algorithm string
+	// Valid is the hex encoded hash of the target.
+	Valid string
+	// Mismatch is a valid hash, but of some other data than target.
+	Mismatch string
+	// Invalid is the Valid hash with its first character removed.
+	Invalid string
+	// Files are the checksum files.
+	Files ChecksumFiles
+}
+
+// checksumFileSuffixes are the suffixes of checksum files, appended to
+// upper case algorithm name followed by SUMS, like SHA256SUMS.txt.
+var checksumFileSuffixes = map[string]string{
+	"Valid":        ".txt",
+	"Invalid":      ".invalid.txt",
+	"Missing":      ".missing.txt",
+	"Mismatch":     ".mismatch.txt",
+	"CRLF":         ".crlf.txt",
+	"BSD":          ".bsd.txt",
+	"Multi":        ".multi.txt",
+	"MultiPrefix":  ".multi-prefix.txt",
+	"Hash":         ".hash.txt",
+	"HashLF":       ".hash.lf-1.txt",
+	"HashLFLF":     ".hash.lf-2.txt",
+	"HashCR":       ".hash.cr-1.txt",
+	"HashCRLF":     ".hash.crlf-1.txt",
+	"HashInvalid":  ".hash.invalid.txt",
+	"HashMismatch": ".hash.mismatch.txt",
+}
+
+var libraryHashFuncRegex = regexp.MustCompile(`(?m)^__lib[a-z]+_hash_([a-z0-9]+)\(\)`)
+
+// LibraryHashAlgorithms returns hash algorithms implemented by the library,
+// detected from its `__lib<name>_hash_<algorithm>` functions. It returns an
+// error if library implements an algorithm fixtures cannot be generated for.
+func LibraryHashAlgorithms(lib string) ([]string, error) {
+	path, err := LibraryPath(lib)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var algorithms []string
+	for _, m := range libraryHashFuncRegex.FindAllSubmatch(data, -1) {
+		name := string(m[1])
+		if name == "verify" {
+			continue
+		}
+		if _, ok := checksumHashes[name]; !ok {
+			return nil, fmt.Errorf("library %s implements unsupported hash algorithm %s", lib, name)
+		}
+		algorithms = append(algorithms, name)
+	}
+	sort.Strings(algorithms)
+	return algorithms, nil
+}
+
+// WriteChecksumFixtures writes checksum fixtures of the target file for the
+// algorithms into the directory. Entries in checksum files refer to target by
+// its path as given. Mismatch hashes are hashes of target contents prefixed
+// with "mismatch\n".
+func WriteChecksumFixtures(dir, target string, algorithms ...string) ([]ChecksumFixture, error) {
+	data, err := ioutil.ReadFile(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []ChecksumFixture
+	for _, algorithm := range algorithms {
+		newHash, ok := checksumHashes[algorithm]
+		if !ok {
+			return nil, fmt.Errorf("unsupported hash algorithm %s", algorithm)
+		}
+
+		f := ChecksumFixture{
+			Algorithm: algorithm,
+			Valid:     hexHash(newHash, data),
+			Mismatch:  hexHash(newHash, append([]byte("mismatch\n"), data...)),
+		}
+		f.Invalid = f.Valid[1:]
+
+		prefix := strings.ToUpper(algorithm) + "SUMS"
+		files := reflect.ValueOf(&f.Files).Elem()
+		for i := 0; i < files.NumField(); i++ {
+			name := files.Type().Field(i).Name
+			files.Field(i).SetString(filepath.ToSlash(filepath.Join(dir, prefix+checksumFileSuffixes[name])))
+		}
+
+		tag := strings.ToUpper(algorithm)
+		contents := map[string]string{
+			f.Files.Valid:    fmt.Sprintf("%s  %s\n", f.Valid, target),
+			f.Files.Invalid:  fmt.Sprintf("z%s  %s\n", f.Invalid, target),
+			f.Files.Missing:  fmt.Sprintf("%s  %s\n", f.Valid, filepath.ToSlash(filepath.Join(filepath.Dir(target), "missing.txt"))),
+			f.Files.Mismatch: fmt.Sprintf("%s  %s\n", f.Mismatch, target),
+			f.Files.CRLF:     fmt.Sprintf("%s  %s\r\n", f.Valid, target),
+			f.Files.BSD:      fmt.Sprintf("%s (%s) = %s\n", tag, target, f.Valid),
+			f.Files.Multi: fmt.Sprintf("%s  %s\n%s  %s\n%s  %s\n",
+				hexHash(newHash, []byte("other")), "other.txt",
+				f.Valid, target,
+				hexHash(newHash, []byte("another")), "another.txt"),
+			f.Files.MultiPrefix: fmt.Sprintf("%s  %s.sig\n%s  %s\n",
+				f.Mismatch, target,
+				f.Valid, target),
+			f.Files.Hash:         f.Valid,
+			f.Files.HashLF:       f.Valid + "\n",
+			f.Files.HashLFLF:     f.Valid + "\n\n",
+			f.Files.HashCR:       f.Valid + "\r",
+			f.Files.HashCRLF:     f.Valid + "\r\n",
+			f.Files.HashInvalid:  fmt.Sprintf("z%s  %s", f.Invalid, target),
+			f.Files.HashMismatch: f.Mismatch,
+		}
+		for path, content := range contents {
+			if err := ioutil.WriteFile(filepath.FromSlash(path), []byte(content), 0644); err != nil {
+				return nil, err
+			}
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// hexHash returns hex encoded hash of the data.
+func hexHash(newHash func() hash.Hash, data []byte) string {
+	h := newHash()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChecksumFixturesSource returns Go source of a package level variable with
+// the name, which is a map of algorithm names to fixtures.
+func ChecksumFixturesSource(pkg, name, generator string, fixtures []ChecksumFixture) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by %s. DO NOT EDIT.\n\n", generator)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "import \"github.com/tprasadtp/shlibs/internal/libtest\"\n\n")
+	fmt.Fprintf(&b, "// %s are checksum fixtures by hash algorithm.\n", name)
+	fmt.Fprintf(&b, "var %s = map[string]libtest.ChecksumFixture{\n", name)
+	for _, f := range fixtures {
+		fmt.Fprintf(&b, "%q: {\n", f.Algorithm)
+		fmt.Fprintf(&b, "Algorithm: %q,\n", f.Algorithm)
+		fmt.Fprintf(&b, "Valid: %q,\n", f.Valid)
+		fmt.Fprintf(&b, "Mismatch: %q,\n", f.Mismatch)
+		fmt.Fprintf(&b, "Invalid: %q,\n", f.Invalid)
+		fmt.Fprintf(&b, "Files: libtest.ChecksumFiles{\n")
+		files := reflect.ValueOf(f.Files)
+		for i := 0; i < files.NumField(); i++ {
+			fmt.Fprintf(&b, "%s: %q,\n", files.Type().Field(i).Name, files.Field(i).String())
+		}
+		fmt.Fprintf(&b, "},\n},\n")
+	}
+	fmt.Fprintf(&b, "}\n")
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,89 @@
+package libtest
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLibraryHashAlgorithms(t *testing.T) {
+	algorithms, err := LibraryHashAlgorithms("dl")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"md5", "sha1", "sha256", "sha512"}, algorithms)
+
+	algorithms, err = LibraryHashAlgorithms("logger")
+	assert.NoError(t, err)
+	assert.Empty(t, algorithms)
+
+	_, err = LibraryHashAlgorithms("no-such-library")
+	assert.Error(t, err)
+}
+
+func TestWriteChecksumFixtures(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	require.NoError(t, ioutil.WriteFile(target, []byte("abc"), 0644))
+
+	fixtures, err := WriteChecksumFixtures(dir, target, "sha256", "md5")
+	require.NoError(t, err)
+	require.Len(t, fixtures, 2)
+
+	f := fixtures[0]
+	valid := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	assert.Equal(t, "sha256", f.Algorithm)
+	assert.Equal(t, valid, f.Valid)
+	assert.Equal(t, valid[1:], f.Invalid)
+	assert.Len(t, f.Mismatch, len(valid))
+	assert.NotEqual(t, valid, f.Mismatch)
+	assert.Equal(t, "900150983cd24fb0d6963f7d28e17f72", fixtures[1].Valid)
+
+	tt := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "valid", path: f.Files.Valid, want: valid + "  " + target + "\n"},
+		{name: "crlf", path: f.Files.CRLF, want: valid + "  " + target + "\r\n"},
+		{name: "bsd", path: f.Files.BSD, want: "SHA256 (" + target + ") = " + valid + "\n"},
+		{name: "mismatch", path: f.Files.Mismatch, want: f.Mismatch + "  " + target + "\n"},
+		{name: "hash", path: f.Files.Hash, want: valid},
+		{name: "hash-lf-2", path: f.Files.HashLFLF, want: valid + "\n\n"},
+		{name: "hash-cr", path: f.Files.HashCR, want: valid + "\r"},
+		{name: "hash-mismatch", path: f.Files.HashMismatch, want: f.Mismatch},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.True(t, strings.HasPrefix(filepath.Base(tc.path), "SHA256SUMS"))
+			data, err := ioutil.ReadFile(tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(data))
+		})
+	}
+
+	_, err = WriteChecksumFixtures(dir, target, "crc32")
+	assert.Error(t, err)
+}
+
+func TestChecksumFixturesSource(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	require.NoError(t, ioutil.WriteFile(target, []byte("abc"), 0644))
+
+	fixtures, err := WriteChecksumFixtures(dir, target, "sha1")
+	require.NoError(t, err)
+
+	src, err := ChecksumFixturesSource("dl", "checksumFixtures", "test", fixtures)
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "", src, 0)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(src), "// Code generated by test. DO NOT EDIT.")
+	assert.Contains(t, string(src), `"sha1": {`)
+	assert.Contains(t, string(src), fixtures[0].Files.MultiPrefix)
+}
@@ -0,0 +1,336 @@
+package libtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateEnv is the environment variable which switches the test binary into
+// fake date helper mode. Its value is the directory of the fake date.
+const dateEnv = "LIBTEST_FAKE_DATE_DIR"
+
+// Clock is the time reported by a fake date command.
+type Clock struct {
+	// Start is the time reported by the first call.
+	Start time.Time `json:"start"`
+	// Step is added to the time on every call, so that Nth call reports
+	// Start + N*Step. Zero keeps the clock fixed.
+	Step time.Duration `json:"step"`
+}
+
+// clockState is the clock of a fake date saved in its directory.
+type clockState struct {
+	Clock
+	// From is the number of calls made before the clock was last set.
+	From int `json:"from"`
+}
+
+// FixedClock returns a clock which always reports the time.
+func FixedClock(t time.Time) Clock {
+	return Clock{Start: t}
+}
+
+// FakeClock is a fake date command installed in a sandbox. Calls of the fake
+// are recorded like those of fake commands.
+type FakeClock struct {
+	*Fake
+}
+
+// FakeDate installs a date command backed by the clock into the bin
+// directory of the sandbox, replacing any host date allowed. It supports
+// `-u`, `--rfc-3339`, `--iso-8601`, `-R` and `+FORMAT` with the conversions
+// of GNU date in the C locale, and honours TZ. Timestamps printed by shell
+// scripts can thus be pinned without faketime, with any date implementation
+// on the host.
+//
+//	sb.FakeDate(libtest.FixedClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+//	r := libtest.Run(t, "sh", []string{"logger"}, `log_info "hello"`, libtest.WithSandbox(sb))
+func (sb *Sandbox) FakeDate(clock Clock) *FakeClock {
+	sb.t.Helper()
+
+	c := &FakeClock{Fake: &Fake{
+		sb:   sb,
+		name: "date",
+		dir:  filepath.Join(sb.dir, "fakes", "date"),
+	}}
+	if err := os.MkdirAll(filepath.Join(c.dir, "calls"), 0755); err != nil {
+		sb.t.Fatalf("failed to create fake date: %s", err)
+	}
+	c.write(clockState{Clock: clock})
+	sb.installStub("date", dateEnv, c.dir)
+	return c
+}
+
+// Now returns the time which the next call of the fake reports.
+func (c *FakeClock) Now() time.Time {
+	c.sb.t.Helper()
+
+	clock, err := readClock(c.dir)
+	if err != nil {
+		c.sb.t.Fatalf("failed to read clock of fake date: %s", err)
+	}
+	return clock.at(len(c.Calls()))
+}
+
+// Set sets the time reported by the next call of the fake. Step of the clock
+// is unchanged.
+func (c *FakeClock) Set(t time.Time) {
+	c.sb.t.Helper()
+
+	clock, err := readClock(c.dir)
+	if err != nil {
+		c.sb.t.Fatalf("failed to read clock of fake date: %s", err)
+	}
+	clock.Start = t
+	clock.From = len(c.Calls())
+	c.write(clock)
+}
+
+// Advance moves the clock forward by the duration.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.sb.t.Helper()
+	c.Set(c.Now().Add(d))
+}
+
+// write saves the clock to the directory of the fake.
+func (c *FakeClock) write(clock clockState) {
+	c.sb.t.Helper()
+
+	data, err := json.Marshal(clock)
+	if err != nil {
+		c.sb.t.Fatalf("failed to encode clock of fake date: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(c.dir, "clock.json"), data, 0644); err != nil {
+		c.sb.t.Fatalf("failed to write clock of fake date: %s", err)
+	}
+}
+
+// readClock reads the clock saved in the fake directory.
+func readClock(dir string) (clockState, error) {
+	var clock clockState
+	data, err := ioutil.ReadFile(filepath.Join(dir, "clock.json"))
+	if err != nil {
+		return clock, err
+	}
+	err = json.Unmarshal(data, &clock)
+	return clock, err
+}
+
+// at returns the time reported by the nth call.
+func (c clockState) at(n int) time.Time {
+	return c.Start.Add(time.Duration(n-c.From) * c.Step)
+}
+
+// runFakeDate records the invocation and prints the time of the clock. It
+// returns the exit code of the fake date.
+func runFakeDate(dir string, args []string, stdout, stderr io.Writer) int {
+	clock, err := readClock(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "libtest: fake date: failed to read clock: %s\n", err)
+		return 125
+	}
+
+	call := FakeCall{Args: args}
+	if call.Args == nil {
+		call.Args = []string{}
+	}
+	call.Dir, _ = os.Getwd()
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, dateEnv+"=") {
+			call.Env = append(call.Env, kv)
+		}
+	}
+
+	f, n, err := reserveFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		fmt.Fprintf(stderr, "libtest: fake date: failed to record call: %s\n", err)
+		return 125
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(call); err != nil {
+		fmt.Fprintf(stderr, "libtest: fake date: failed to record call: %s\n", err)
+		return 125
+	}
+
+	out, err := formatDate(clock.at(n).In(time.Local), args)
+	if err != nil {
+		fmt.Fprintf(stderr, "libtest: fake date: %s\n", err)
+		return 125
+	}
+	io.WriteString(stdout, out+"\n")
+	return 0
+}
+
+// defaultDateFormat is the output format of date without arguments in the
+// C locale.
+const defaultDateFormat = "+%a %b %e %H:%M:%S %Z %Y"
+
+// formatDate formats the time like date invoked with the arguments would.
+func formatDate(t time.Time, args []string) (string, error) {
+	format := defaultDateFormat
+	for _, arg := range args {
+		name, value := arg, ""
+		if i := strings.IndexByte(arg, '='); i > 0 && strings.HasPrefix(arg, "--") {
+			name, value = arg[:i], arg[i+1:]
+		}
+		switch {
+		case strings.HasPrefix(arg, "+"):
+			format = arg
+		case arg == "-u" || arg == "--utc" || arg == "--universal":
+			t = t.UTC()
+		case arg == "-R" || arg == "--rfc-email":
+			format = "+%a, %d %b %Y %H:%M:%S %z"
+		case name == "--rfc-3339":
+			switch {
+			case isPrefix(value, "date"):
+				format = "+%F"
+			case isPrefix(value, "seconds"):
+				format = "+%F %T%:z"
+			case isPrefix(value, "ns"):
+				format = "+%F %T.%N%:z"
+			default:
+				return "", fmt.Errorf("invalid argument %q for --rfc-3339", value)
+			}
+		case strings.HasPrefix(arg, "-I") || name == "--iso-8601":
+			if strings.HasPrefix(arg, "-I") {
+				value = arg[2:]
+			}
+			switch {
+			case value == "" || isPrefix(value, "date"):
+				format = "+%F"
+			case isPrefix(value, "hours"):
+				format = "+%FT%H%:z"
+			case isPrefix(value, "minutes"):
+				format = "+%FT%H:%M%:z"
+			case isPrefix(value, "seconds"):
+				format = "+%FT%T%:z"
+			case isPrefix(value, "ns"):
+				format = "+%FT%T,%N%:z"
+			default:
+				return "", fmt.Errorf("invalid argument %q for --iso-8601", value)
+			}
+		default:
+			return "", fmt.Errorf("unsupported argument %q", arg)
+		}
+	}
+	return strftime(t, format[1:]), nil
+}
+
+// isPrefix returns true if s is a non empty prefix of word, as date accepts
+// abbreviated arguments like `--rfc-3339=s`.
+func isPrefix(s, word string) bool {
+	return s != "" && strings.HasPrefix(word, s)
+}
+
+// strftime formats the time with the conversions of GNU date in the C
+// locale. Unknown conversions are printed as is.
+func strftime(t time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case '%':
+			b.WriteByte('%')
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'b', 'h':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case 'c':
+			b.WriteString(strftime(t, "%a %b %e %H:%M:%S %Y"))
+		case 'C':
+			fmt.Fprintf(&b, "%02d", t.Year()/100)
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'D':
+			b.WriteString(strftime(t, "%m/%d/%y"))
+		case 'e':
+			fmt.Fprintf(&b, "%2d", t.Day())
+		case 'F':
+			b.WriteString(strftime(t, "%Y-%m-%d"))
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'I':
+			fmt.Fprintf(&b, "%02d", hour12(t))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'k':
+			fmt.Fprintf(&b, "%2d", t.Hour())
+		case 'l':
+			fmt.Fprintf(&b, "%2d", hour12(t))
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'n':
+			b.WriteByte('\n')
+		case 'N':
+			fmt.Fprintf(&b, "%09d", t.Nanosecond())
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'P':
+			b.WriteString(strings.ToLower(t.Format("PM")))
+		case 'r':
+			b.WriteString(strftime(t, "%I:%M:%S %p"))
+		case 'R':
+			b.WriteString(strftime(t, "%H:%M"))
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 't':
+			b.WriteByte('\t')
+		case 'T':
+			b.WriteString(strftime(t, "%H:%M:%S"))
+		case 'u':
+			wd := int(t.Weekday())
+			if wd == 0 {
+				wd = 7
+			}
+			b.WriteString(strconv.Itoa(wd))
+		case 'w':
+			b.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'Y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case ':':
+			if strings.HasPrefix(format[i:], ":z") {
+				b.WriteString(t.Format("-07:00"))
+				i++
+			} else {
+				b.WriteString("%:")
+			}
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String()
+}
+
+// hour12 returns the hour of the time in 12 hour clock.
+func hour12(t time.Time) int {
+	if h := t.Hour() % 12; h != 0 {
+		return h
+	}
+	return 12
+}
@@ -0,0 +1,97 @@
+package libtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatDate(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	ts := time.Date(2000, 1, 2, 15, 4, 5, 60708, ist)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+		err  bool
+	}{
+		{name: "default", want: "Sun Jan  2 15:04:05 IST 2000"},
+		{name: "rfc-3339-seconds", args: []string{"--rfc-3339=seconds"}, want: "2000-01-02 15:04:05+05:30"},
+		{name: "rfc-3339-abbreviated", args: []string{"--rfc-3339=s"}, want: "2000-01-02 15:04:05+05:30"},
+		{name: "rfc-3339-date", args: []string{"--rfc-3339=date"}, want: "2000-01-02"},
+		{name: "rfc-3339-ns", args: []string{"--rfc-3339=ns"}, want: "2000-01-02 15:04:05.000060708+05:30"},
+		{name: "rfc-3339-utc", args: []string{"-u", "--rfc-3339=s"}, want: "2000-01-02 09:34:05+00:00"},
+		{name: "iso-8601", args: []string{"--iso-8601"}, want: "2000-01-02"},
+		{name: "iso-8601-minutes", args: []string{"-Iminutes"}, want: "2000-01-02T15:04+05:30"},
+		{name: "iso-8601-ns", args: []string{"--iso-8601=ns", "--utc"}, want: "2000-01-02T09:34:05,000060708+00:00"},
+		{name: "rfc-email", args: []string{"-R"}, want: "Sun, 02 Jan 2000 15:04:05 +0530"},
+		{name: "format", args: []string{"+%Y%m%d-%H%M%S %z %Z %s %%"}, want: "20000102-150405 +0530 IST 946805645 %"},
+		{name: "format-12-hour", args: []string{"+%I %l %p %P %r"}, want: "03  3 PM pm 03:04:05 PM"},
+		{name: "format-calendar", args: []string{"+%a %A %b %B %h %C %y %j %u %w %e %k"}, want: "Sun Sunday Jan January Jan 20 00 002 7 0  2 15"},
+		{name: "format-composite", args: []string{"+%c|%D|%F|%T|%R"}, want: "Sun Jan  2 15:04:05 2000|01/02/00|2000-01-02|15:04:05|15:04"},
+		{name: "format-unknown", args: []string{"+%Q %: %"}, want: "%Q %: %"},
+		{name: "format-last-wins", args: []string{"--rfc-3339=s", "+%s"}, want: "946805645"},
+		{name: "invalid-rfc-3339", args: []string{"--rfc-3339=weeks"}, err: true},
+		{name: "unsupported", args: []string{"-d", "yesterday"}, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := formatDate(ts, tc.args)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFakeDate(t *testing.T) {
+	sb := NewSandbox(t)
+	date := sb.FakeDate(FixedClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	r := Run(t, "sh", nil, `date --rfc-3339=s; date -u "+%H:%M"; TZ=Asia/Kolkata date --rfc-3339=s`,
+		WithSandbox(sb),
+		WithEnv("TZ=UTC"),
+	)
+	assert.Equal(t, 0, r.ExitCode)
+	assert.Empty(t, r.Stderr)
+	assert.Equal(t, "2000-01-01 00:00:00+00:00\n00:00\n2000-01-01 05:30:00+05:30\n", r.Stdout)
+
+	calls := date.Calls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, []string{"-u", "+%H:%M"}, calls[1].Args)
+	assert.Equal(t, "", calls[0].Getenv(dateEnv))
+}
+
+func TestFakeDateAdvancing(t *testing.T) {
+	sb := NewSandbox(t)
+	start := time.Date(2000, 1, 1, 23, 59, 59, 0, time.UTC)
+	date := sb.FakeDate(Clock{Start: start, Step: 500 * time.Millisecond})
+
+	r := Run(t, "sh", nil, `date -u +%T.%N; date -u +%T.%N; date -u --rfc-3339=s`, WithSandbox(sb))
+	assert.Equal(t, "23:59:59.000000000\n23:59:59.500000000\n2000-01-02 00:00:00+00:00\n", r.Stdout)
+	assert.Equal(t, start.Add(1500*time.Millisecond), date.Now())
+
+	date.Advance(time.Hour)
+	r = Run(t, "sh", nil, `date -u +%T; date -u +%T`, WithSandbox(sb))
+	assert.Equal(t, "01:00:00\n01:00:01\n", r.Stdout)
+
+	date.Set(start)
+	r = Run(t, "sh", nil, `date -u +%T`, WithSandbox(sb))
+	assert.Equal(t, "23:59:59\n", r.Stdout)
+}
+
+func TestFakeDateUnsupported(t *testing.T) {
+	sb := NewSandbox(t)
+	sb.FakeDate(FixedClock(time.Unix(0, 0)))
+
+	r := Run(t, "sh", nil, `date -d yesterday`, WithSandbox(sb))
+	assert.Equal(t, 125, r.ExitCode)
+	assert.Empty(t, r.Stdout)
+	assert.Contains(t, r.Stderr, `unsupported argument "-d"`)
+}
@@ -0,0 +1,56 @@
+// Command gen-checksum-fixtures generates checksum fixtures of a target file
+// for all the hash algorithms implemented by a shell library, along with a Go
+// source file containing a typed table of the fixtures. It is meant to be run
+// via go generate from the package directory.
+//
+//	//go:generate go run ../internal/libtest/cmd/gen-checksum-fixtures -lib dl -target testdata/checksum.txt -out hash_data_test.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func main() {
+	lib := flag.String("lib", "", "name of the shell library, like dl")
+	target := flag.String("target", "", "file to generate checksums of")
+	dir := flag.String("dir", "testdata", "directory to write checksum files to")
+	out := flag.String("out", "", "go file to write the fixture table to")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the go file")
+	name := flag.String("var", "checksumFixtures", "variable name of the fixture table")
+	flag.Parse()
+
+	if err := run(*lib, *target, *dir, *out, *pkg, *name); err != nil {
+		fmt.Fprintf(os.Stderr, "gen-checksum-fixtures: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(lib, target, dir, out, pkg, name string) error {
+	if lib == "" || target == "" || out == "" || pkg == "" {
+		return fmt.Errorf("-lib, -target, -out and -pkg are required")
+	}
+
+	algorithms, err := libtest.LibraryHashAlgorithms(lib)
+	if err != nil {
+		return err
+	}
+	if len(algorithms) == 0 {
+		return fmt.Errorf("library %s does not implement any hash algorithms", lib)
+	}
+
+	fixtures, err := libtest.WriteChecksumFixtures(dir, target, algorithms...)
+	if err != nil {
+		return err
+	}
+
+	src, err := libtest.ChecksumFixturesSource(pkg, name, "gen-checksum-fixtures", fixtures)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
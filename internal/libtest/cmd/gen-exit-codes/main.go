@@ -0,0 +1,48 @@
+// Command gen-exit-codes generates a JSON catalog of the exit codes of a
+// shell library, with the messages its error explaining function prints for
+// them and the functions returning them, for use by downstream tools. It is
+// meant to be run via go generate from the package directory.
+//
+//	//go:generate go run ../internal/libtest/cmd/gen-exit-codes -lib dl -func shlib_explain_error -out exit-codes.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func main() {
+	lib := flag.String("lib", "", "name of the shell library, like dl")
+	explain := flag.String("func", "", "name of the function explaining exit codes")
+	out := flag.String("out", "", "file to write the catalog to")
+	flag.Parse()
+
+	if err := run(*lib, *explain, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "gen-exit-codes: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(lib, explain, out string) error {
+	if lib == "" || explain == "" || out == "" {
+		return fmt.Errorf("-lib, -func and -out are required")
+	}
+
+	catalog, err := libtest.ParseExitCodes(lib, explain)
+	if err != nil {
+		return err
+	}
+	if codes := catalog.Undocumented(); len(codes) > 0 {
+		fmt.Fprintf(os.Stderr, "gen-exit-codes: warning: %d exit codes are not documented by %s\n", len(codes), explain)
+	}
+
+	data, err := catalog.JSON()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, data, 0644)
+}
@@ -0,0 +1,220 @@
+// Command mutate-libs runs mutation testing of shell libraries. It applies
+// small changes to a library, like swapping -eq and -ne, changing return
+// codes, dropping negations and deleting log calls, runs tests of the package
+// owning the library against each mutant, and reports mutants which did not
+// make any test fail. Tests run in copies of the repository, so the working
+// tree is never modified. Tests failing without any mutation are ignored.
+//
+//	go run ./internal/libtest/cmd/mutate-libs -lib dl -func __libdl_hash_verify -shells bash
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func main() {
+	libs := flag.String("lib", "dl,logger", "comma separated list of libraries to mutate")
+	kindsFlag := flag.String("kinds", strings.Join(kinds, ","), "comma separated list of mutation kinds")
+	fn := flag.String("func", "", "only mutate functions matching the regular expression")
+	run := flag.String("run", "", "only run tests matching the regular expression")
+	shells := flag.String("shells", "", "comma separated list of shells to test with, defaults to all")
+	parallel := flag.Int("parallel", 2, "number of mutants to test in parallel")
+	timeout := flag.Duration("timeout", 10*time.Minute, "timeout of tests of a single mutant")
+	list := flag.Bool("list", false, "only list mutants without running tests")
+	flag.Parse()
+
+	cfg := config{
+		kinds:    make(map[string]bool),
+		run:      *run,
+		shells:   *shells,
+		parallel: *parallel,
+		timeout:  *timeout,
+		list:     *list,
+	}
+	for _, kind := range strings.Split(*kindsFlag, ",") {
+		cfg.kinds[strings.TrimSpace(kind)] = true
+	}
+	if *fn != "" {
+		re, err := regexp.Compile(*fn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mutate-libs: invalid -func: %s\n", err)
+			os.Exit(1)
+		}
+		cfg.fn = re
+	}
+	if cfg.parallel < 1 {
+		cfg.parallel = 1
+	}
+
+	for _, lib := range strings.Split(*libs, ",") {
+		if err := mutateLib(strings.TrimSpace(lib), cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "mutate-libs: %s: %s\n", lib, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// config holds the options of the command.
+type config struct {
+	kinds    map[string]bool
+	fn       *regexp.Regexp
+	run      string
+	shells   string
+	parallel int
+	timeout  time.Duration
+	list     bool
+}
+
+// repoRoot returns the directory containing go.mod, starting from the
+// directory of the library.
+func repoRoot(path string) (string, error) {
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// mutateLib tests all the mutants of the library and reports survivors.
+func mutateLib(lib string, cfg config) error {
+	path, err := libtest.LibraryPath(lib)
+	if err != nil {
+		return err
+	}
+	root, err := repoRoot(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	// Library is tested by the package in the same directory.
+	pkg := "./" + filepath.ToSlash(filepath.Dir(rel))
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	all, err := mutants(rel, src, cfg.kinds)
+	if err != nil {
+		return err
+	}
+	var selected []mutant
+	for _, m := range all {
+		if cfg.fn == nil || cfg.fn.MatchString(m.fn) {
+			selected = append(selected, m)
+		}
+	}
+
+	if len(selected) == 0 {
+		fmt.Printf("%s: no mutants\n", filepath.ToSlash(rel))
+		return nil
+	}
+	if cfg.list {
+		for _, m := range selected {
+			fmt.Printf("%s:%s\n", filepath.ToSlash(rel), m)
+		}
+		return nil
+	}
+
+	runners := make([]*runner, cfg.parallel)
+	for i := range runners {
+		r, err := newRunner(root, pkg, cfg.run, cfg.shells, cfg.timeout)
+		if err != nil {
+			return err
+		}
+		defer r.close()
+		runners[i] = r
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: running baseline tests of %s\n", rel, pkg)
+	baseline, err := runners[0].test()
+	if err != nil {
+		return err
+	}
+	if baseline.timedOut {
+		return fmt.Errorf("baseline tests of %s timed out", pkg)
+	}
+	if !baseline.ok && len(baseline.failed) == 0 {
+		return fmt.Errorf("baseline tests of %s failed:\n%s", pkg, baseline.output)
+	}
+	if n := len(baseline.failed); n > 0 {
+		fmt.Fprintf(os.Stderr, "%s: ignoring %d tests failing without mutations\n", rel, n)
+	}
+
+	survived := make([]bool, len(selected))
+	errs := make([]error, len(selected))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for _, r := range runners {
+		wg.Add(1)
+		go func(r *runner) {
+			defer wg.Done()
+			for i := range jobs {
+				m := selected[i]
+				result, err := r.testMutant(rel, src, m)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				tests, killed := killedBy(baseline, result)
+				survived[i] = !killed
+
+				mu.Lock()
+				done++
+				status := "survived"
+				if killed {
+					status = "killed by " + strings.Join(tests, ", ")
+				}
+				fmt.Fprintf(os.Stderr, "[%d/%d] %s:%s: %s\n", done, len(selected), filepath.ToSlash(rel), m, status)
+				mu.Unlock()
+			}
+		}(r)
+	}
+	for i := range selected {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	for i, m := range selected {
+		if survived[i] {
+			count++
+			fmt.Printf("%s:%s\n", filepath.ToSlash(rel), m)
+		}
+	}
+	score := 100.0
+	if len(selected) > 0 {
+		score = 100 * float64(len(selected)-count) / float64(len(selected))
+	}
+	fmt.Printf("%s: %d mutants, %d killed, %d survived, mutation score %.1f%%\n",
+		filepath.ToSlash(rel), len(selected), len(selected)-count, count, score)
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Mutation kinds.
+const (
+	kindComparison = "comparison"
+	kindReturn     = "return"
+	kindNegation   = "negation"
+	kindLog        = "log"
+)
+
+// kinds are all the mutation kinds.
+var kinds = []string{kindComparison, kindReturn, kindNegation, kindLog}
+
+// comparisonSwaps are the replacements of comparison operators in test
+// commands.
+var comparisonSwaps = map[string]string{
+	"-eq": "-ne",
+	"-ne": "-eq",
+	"-lt": "-ge",
+	"-ge": "-lt",
+	"-gt": "-le",
+	"-le": "-gt",
+	"=":   "!=",
+	"==":  "!=",
+	"!=":  "=",
+	"-z":  "-n",
+	"-n":  "-z",
+}
+
+// mutant is a single change to the source of a library.
+type mutant struct {
+	// kind of the mutation.
+	kind string
+	// line and col of the change, 1 based.
+	line int
+	col  int
+	// function the change is in, or empty at top level.
+	fn string
+	// start and end are byte offsets of the source replaced with repl.
+	start int
+	end   int
+	repl  string
+	// desc describes the change, like "-ne -> -eq".
+	desc string
+}
+
+// String returns the location and description of the mutant.
+func (m mutant) String() string {
+	s := fmt.Sprintf("%d:%d: %s: %s", m.line, m.col, m.kind, m.desc)
+	if m.fn != "" {
+		s += " (in " + m.fn + ")"
+	}
+	return s
+}
+
+// apply returns the source with the mutation applied.
+func (m mutant) apply(src []byte) []byte {
+	var b bytes.Buffer
+	b.Write(src[:m.start])
+	b.WriteString(m.repl)
+	b.Write(src[m.end:])
+	return b.Bytes()
+}
+
+// mutants parses the shell script and returns all the mutants of the
+// given kinds, in order of their position.
+func mutants(name string, src []byte, enabled map[string]bool) ([]mutant, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(bytes.NewReader(src), name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []mutant
+	var stack []syntax.Node
+	add := func(kind string, start, end syntax.Pos, repl, desc string) {
+		if !enabled[kind] {
+			return
+		}
+		m := mutant{
+			kind:  kind,
+			line:  int(start.Line()),
+			col:   int(start.Col()),
+			start: int(start.Offset()),
+			end:   int(end.Offset()),
+			repl:  repl,
+			desc:  desc,
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if decl, ok := stack[i].(*syntax.FuncDecl); ok {
+				m.fn = decl.Name.Value
+				break
+			}
+		}
+		result = append(result, m)
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			if n.Negated {
+				// Position of negated statements is the position of `!`.
+				end := syntax.NewPos(n.Position.Offset()+1, n.Position.Line(), n.Position.Col()+1)
+				add(kindNegation, n.Position, end, "", "drop !")
+			}
+			if call, ok := n.Cmd.(*syntax.CallExpr); ok && len(call.Args) > 0 {
+				if name := call.Args[0].Lit(); strings.HasPrefix(name, "log_") {
+					add(kindLog, call.Pos(), call.End(), ":", "delete "+name)
+				}
+			}
+		case *syntax.CallExpr:
+			if len(n.Args) == 0 {
+				return true
+			}
+			switch n.Args[0].Lit() {
+			case "[", "test":
+				for _, arg := range n.Args[1:] {
+					lit := arg.Lit()
+					if repl, ok := comparisonSwaps[lit]; ok {
+						add(kindComparison, arg.Pos(), arg.End(), repl, lit+" -> "+repl)
+					}
+					if lit == "!" {
+						add(kindNegation, arg.Pos(), arg.End(), "", "drop !")
+					}
+				}
+			case "return", "exit":
+				if len(n.Args) < 2 {
+					return true
+				}
+				code, err := strconv.Atoi(n.Args[1].Lit())
+				if err != nil {
+					return true
+				}
+				repl := strconv.Itoa(code + 1)
+				if code == 255 {
+					repl = "0"
+				}
+				add(kindReturn, n.Args[1].Pos(), n.Args[1].End(), repl,
+					fmt.Sprintf("%s %d -> %s %s", n.Args[0].Lit(), code, n.Args[0].Lit(), repl))
+			}
+		case *syntax.BinaryTest:
+			op := n.Op.String()
+			if repl, ok := comparisonSwaps[op]; ok {
+				end := syntax.NewPos(n.OpPos.Offset()+uint(len(op)), n.OpPos.Line(), n.OpPos.Col()+uint(len(op)))
+				add(kindComparison, n.OpPos, end, repl, op+" -> "+repl)
+			}
+		case *syntax.UnaryTest:
+			op := n.Op.String()
+			if repl, ok := comparisonSwaps[op]; ok {
+				end := syntax.NewPos(n.OpPos.Offset()+uint(len(op)), n.OpPos.Line(), n.OpPos.Col()+uint(len(op)))
+				add(kindComparison, n.OpPos, end, repl, op+" -> "+repl)
+			}
+			if n.Op == syntax.TsNot {
+				end := syntax.NewPos(n.OpPos.Offset()+1, n.OpPos.Line(), n.OpPos.Col()+1)
+				add(kindNegation, n.OpPos, end, "", "drop !")
+			}
+		}
+		return true
+	})
+	return result, nil
+}
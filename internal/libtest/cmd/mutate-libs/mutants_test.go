@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+const mutantsScript = `f() {
+    if [ "$#" -ne 1 ]; then
+        log_error "Invalid arguments"; return 12
+    fi
+    if ! g "$1"; then
+        return 1
+    fi
+    [[ ! -z "$1" ]] && exit 0
+}
+log_info hello 2>&1
+`
+
+func TestMutants(t *testing.T) {
+	enabled := make(map[string]bool)
+	for _, kind := range kinds {
+		enabled[kind] = true
+	}
+
+	got, err := mutants("script.sh", []byte(mutantsScript), enabled)
+	require.NoError(t, err)
+
+	tests := []struct {
+		mutant string
+		line   string
+	}{
+		{
+			mutant: "2:15: comparison: -ne -> -eq (in f)",
+			line:   `    if [ "$#" -eq 1 ]; then`,
+		},
+		{
+			mutant: "3:9: log: delete log_error (in f)",
+			line:   `        :; return 12`,
+		},
+		{
+			mutant: "3:47: return: return 12 -> return 13 (in f)",
+			line:   `        log_error "Invalid arguments"; return 13`,
+		},
+		{
+			mutant: "5:8: negation: drop ! (in f)",
+			line:   `    if  g "$1"; then`,
+		},
+		{
+			mutant: "6:16: return: return 1 -> return 2 (in f)",
+			line:   `        return 2`,
+		},
+		{
+			mutant: "8:8: negation: drop ! (in f)",
+			line:   `    [[  -z "$1" ]] && exit 0`,
+		},
+		{
+			mutant: "8:10: comparison: -z -> -n (in f)",
+			line:   `    [[ ! -n "$1" ]] && exit 0`,
+		},
+		{
+			mutant: "8:29: return: exit 0 -> exit 1 (in f)",
+			line:   `    [[ ! -z "$1" ]] && exit 1`,
+		},
+		{
+			mutant: "10:1: log: delete log_info",
+			line:   `: 2>&1`,
+		},
+	}
+
+	require.Len(t, got, len(tests))
+	for i, tc := range tests {
+		m := got[i]
+		t.Run(tc.mutant, func(t *testing.T) {
+			assert.Equal(t, tc.mutant, m.String())
+
+			mutated := m.apply([]byte(mutantsScript))
+			lines := bytes.Split(mutated, []byte("\n"))
+			assert.Equal(t, tc.line, string(lines[m.line-1]))
+
+			_, err := syntax.NewParser().Parse(bytes.NewReader(mutated), "mutant.sh")
+			assert.NoError(t, err, "mutant must be valid shell")
+		})
+	}
+}
+
+func TestMutantsKinds(t *testing.T) {
+	got, err := mutants("script.sh", []byte(mutantsScript), map[string]bool{kindReturn: true})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	for _, m := range got {
+		assert.Equal(t, kindReturn, m.kind)
+	}
+}
+
+func TestKilledBy(t *testing.T) {
+	baseline := testRun{failed: map[string]bool{"TestBroken": true}}
+
+	tests := []struct {
+		name   string
+		run    testRun
+		killed bool
+		by     []string
+	}{
+		{name: "passed", run: testRun{ok: true}},
+		{name: "same-failures", run: testRun{failed: map[string]bool{"TestBroken": true}}},
+		{
+			name:   "new-failure",
+			run:    testRun{failed: map[string]bool{"TestBroken": true, "TestB": true, "TestA": true, "TestA/sub": true}},
+			killed: true,
+			by:     []string{"TestA", "TestB"},
+		},
+		{name: "timeout", run: testRun{timedOut: true}, killed: true, by: []string{"(timeout)"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			by, killed := killedBy(baseline, tc.run)
+			assert.Equal(t, tc.killed, killed)
+			assert.Equal(t, tc.by, by)
+		})
+	}
+
+	t.Run("package-failure-with-passing-baseline", func(t *testing.T) {
+		by, killed := killedBy(testRun{ok: true}, testRun{failed: map[string]bool{}})
+		assert.True(t, killed)
+		assert.Equal(t, []string{"(package)"}, by)
+	})
+}
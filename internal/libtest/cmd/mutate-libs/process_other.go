@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package main
+
+import "os/exec"
+
+// setProcessGroup is not supported on this platform and does nothing.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killGroup kills the started command. Processes started by it are not
+// killed on this platform.
+func killGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
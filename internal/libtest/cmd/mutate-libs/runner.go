@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// testRun is the result of running tests of a package.
+type testRun struct {
+	// failed are names of the tests which failed.
+	failed map[string]bool
+	// ok is true if the package passed.
+	ok bool
+	// timedOut is true if tests did not complete within the timeout.
+	timedOut bool
+	// output of go test, for reporting errors.
+	output string
+}
+
+// runner runs tests of a package in a copy of the repository.
+type runner struct {
+	// dir is the root of the copy of the repository.
+	dir     string
+	pkg     string
+	run     string
+	shells  string
+	timeout time.Duration
+}
+
+// goTestEvent is an event printed by go test -json.
+type goTestEvent struct {
+	Action string
+	Test   string
+	Output string
+}
+
+// test runs go test for the package and returns the failed tests.
+func (r *runner) test() (testRun, error) {
+	args := []string{"test", "-json", "-count=1"}
+	if r.run != "" {
+		args = append(args, "-run", r.run)
+	}
+	args = append(args, r.pkg)
+
+	// go test is killed along with its process group on timeout, as the test
+	// binary it started would otherwise keep running after go test exits.
+	cmd := exec.Command("go", args...)
+	setProcessGroup(cmd)
+	cmd.Dir = r.dir
+	cmd.Env = os.Environ()
+	if r.shells != "" {
+		cmd.Env = append(cmd.Env, "SHLIBS_TEST_SHELLS="+r.shells)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return testRun{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return testRun{}, err
+	}
+	var timedOut int32
+	timer := time.AfterFunc(r.timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		killGroup(cmd)
+	})
+
+	result := testRun{failed: make(map[string]bool)}
+	var output strings.Builder
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event goTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// build errors are not printed as json.
+			output.WriteString(scanner.Text() + "\n")
+			continue
+		}
+		output.WriteString(event.Output)
+		if event.Action == "fail" && event.Test != "" {
+			result.failed[event.Test] = true
+		}
+	}
+	err = cmd.Wait()
+	timer.Stop()
+	output.WriteString(stderr.String())
+	result.output = output.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case atomic.LoadInt32(&timedOut) == 1:
+		result.timedOut = true
+		return result, nil
+	case err == nil:
+		result.ok = true
+		return result, nil
+	case errors.As(err, &exitErr):
+		return result, nil
+	default:
+		return result, fmt.Errorf("failed to run go test: %w", err)
+	}
+}
+
+// killedBy returns the top level tests which failed in the run, but not in
+// the baseline, and whether the mutant was killed. Mutants which break the
+// package without failing a test, like by timing out, are killed as well.
+func killedBy(baseline, run testRun) ([]string, bool) {
+	if run.ok {
+		return nil, false
+	}
+	if run.timedOut {
+		return []string{"(timeout)"}, true
+	}
+
+	// Only top level tests are reported, subtests fail along with them.
+	top := make(map[string]bool)
+	for test := range run.failed {
+		if !baseline.failed[test] {
+			top[strings.SplitN(test, "/", 2)[0]] = true
+		}
+	}
+	var tests []string
+	for test := range top {
+		tests = append(tests, test)
+	}
+	sort.Strings(tests)
+	if len(tests) > 0 {
+		return tests, true
+	}
+	// package failed, but no test did, like on panics.
+	if len(run.failed) == 0 && baseline.ok {
+		return []string{"(package)"}, true
+	}
+	return nil, false
+}
+
+// copyTree copies the repository into the directory, skipping .git.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir() && info.Name() == ".git":
+			return filepath.SkipDir
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// newRunner copies the repository into a temporary directory.
+func newRunner(root, pkg, run, shells string, timeout time.Duration) (*runner, error) {
+	dir, err := ioutil.TempDir("", "mutate-libs-")
+	if err != nil {
+		return nil, err
+	}
+	if err := copyTree(root, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to copy repository: %w", err)
+	}
+	return &runner{dir: dir, pkg: pkg, run: run, shells: shells, timeout: timeout}, nil
+}
+
+// testMutant writes the mutated library, runs the tests and restores the
+// library.
+func (r *runner) testMutant(lib string, src []byte, m mutant) (testRun, error) {
+	path := filepath.Join(r.dir, lib)
+	if err := ioutil.WriteFile(path, m.apply(src), 0644); err != nil {
+		return testRun{}, err
+	}
+	defer ioutil.WriteFile(path, src, 0644)
+	return r.test()
+}
+
+// close removes the copy of the repository.
+func (r *runner) close() error {
+	return os.RemoveAll(r.dir)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangTest writes the pid of the test binary to the file named by HANG_PID
+// and hangs.
+const hangTest = `package hang
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHang(t *testing.T) {
+	if path := os.Getenv("HANG_PID"); path != "" {
+		ioutil.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644)
+	}
+	time.Sleep(time.Hour)
+}
+`
+
+func TestRunnerTimeout(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skipf("go is not available: %s", err)
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module hang\n\ngo 1.17\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "hang_test.go"), []byte(hangTest), 0644))
+
+	// build the test binary first, so that the timeout expires while it runs.
+	build := exec.Command("go", "test", "-count=1", "-run", "XXX", ".")
+	build.Dir = dir
+	out, err := build.CombinedOutput()
+	require.NoError(t, err, "%s", out)
+
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	t.Setenv("HANG_PID", pidFile)
+
+	r := &runner{dir: dir, pkg: ".", timeout: 2 * time.Second}
+	done := make(chan testRun, 1)
+	go func() {
+		run, err := r.test()
+		assert.NoError(t, err)
+		done <- run
+	}()
+
+	select {
+	case run := <-done:
+		assert.True(t, run.timedOut)
+		assert.False(t, run.ok)
+	case <-time.After(time.Minute):
+		t.Fatal("runner did not return after the timeout")
+	}
+
+	// the test binary must be killed along with go test.
+	data, err := ioutil.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(string(data))
+	require.NoError(t, err)
+	p, err := os.FindProcess(pid)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return p.Signal(syscall.Signal(0)) != nil
+	}, 10*time.Second, 100*time.Millisecond, "test binary %d is still running", pid)
+}
@@ -17,17 +17,6 @@ func AssertCommandAvailable(t *testing.T, cmd string) {
 	assert.Nil(t, err)
 }
 
-func AssertShellsAvailable(t *testing.T) {
-	for _, shell := range SupportedShells() {
-		_, err := exec.LookPath(shell)
-		assert.Nil(t, err)
-	}
-}
-
-func SupportedShells() [4]string {
-	return [4]string{"bash", "sh", "zsh", "dash"}
-}
-
 func UnameM() string {
 	cmd := exec.Command("uname", "-m")
 	out, err := cmd.CombinedOutput()
@@ -48,7 +37,7 @@ func UnameS() string {
 	}
 }
 
-func PrintCmdDebug(t *testing.T, cmd *exec.Cmd) {
+func PrintCmdDebug(t testing.TB, cmd *exec.Cmd) {
 	if os.Getenv("DEBUG") == "1" {
 		t.Log(cmd.String())
 	}
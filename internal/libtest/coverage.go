@@ -0,0 +1,564 @@
+package libtest
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// CoverageEnv is the environment variable which enables line coverage of
+// shell libraries. It is the directory reports are written to. Only bash
+// exposes the file being executed to xtrace, so invocations of other shells
+// do not contribute to coverage.
+//
+//	SHLIBS_TEST_COVERAGE=$(pwd)/coverage go test ./...
+const CoverageEnv = "SHLIBS_TEST_COVERAGE"
+
+// coverageFD is the file descriptor bash writes traces to, so that traces
+// do not end up in stderr of the script.
+const coverageFD = 9
+
+// coveragePS4 is the xtrace prompt, which encodes file and line of every
+// command executed. Bash repeats the first character for each level of
+// nesting, like command substitutions.
+const coveragePS4 = `+libtest-coverage:${BASH_SOURCE}:${LINENO}: `
+
+var coverageTraceRegex = regexp.MustCompile(`^\++libtest-coverage:(.+):([0-9]+): `)
+
+// coveragePrologue enables xtrace if the shell is bash.
+var coveragePrologue = fmt.Sprintf(`[ -n "${BASH_VERSION}" ] && { BASH_XTRACEFD=%d; PS4=%s; set -x; }; `,
+	coverageFD, ShellQuote(coveragePS4))
+
+// coverageDir returns the directory coverage reports are written to, and
+// whether coverage is enabled.
+func coverageDir() (string, bool) {
+	dir := os.Getenv(CoverageEnv)
+	return dir, dir != ""
+}
+
+// coverage is the number of times lines of the shell libraries were traced
+// by file and line.
+type coverage struct {
+	mu   sync.Mutex
+	hits map[string]map[int]int
+}
+
+// shellCoverage collects traces of all the invocations in the test binary.
+var shellCoverage = newCoverage()
+
+func newCoverage() *coverage {
+	return &coverage{hits: make(map[string]map[int]int)}
+}
+
+// record parses the xtrace output and adds hits of the files of known
+// libraries. Traces of the script itself and other files are ignored.
+func (c *coverage) record(r io.Reader) error {
+	files, err := libraryFiles()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		m := coverageTraceRegex.FindStringSubmatch(scanner.Text())
+		if m == nil || !files[m[1]] {
+			continue
+		}
+		line, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		if c.hits[m[1]] == nil {
+			c.hits[m[1]] = make(map[int]int)
+		}
+		c.hits[m[1]][line]++
+	}
+	return scanner.Err()
+}
+
+// libraryFiles returns absolute paths of the known libraries.
+func libraryFiles() (map[string]bool, error) {
+	root, err := repoRoot()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, lib := range libraries {
+		files[filepath.Join(root, lib.path)] = true
+	}
+	return files, nil
+}
+
+// traceCoverage prepares the command to write traces to a file if coverage
+// is enabled. Returned function records the traces once the command exits.
+func traceCoverage(cmd *exec.Cmd, dir string) (func() error, error) {
+	f, err := ioutil.TempFile(dir, "trace-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coverage trace file: %w", err)
+	}
+	cmd.ExtraFiles = make([]*os.File, coverageFD-2)
+	cmd.ExtraFiles[coverageFD-3] = f
+	return func() error {
+		defer f.Close()
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return shellCoverage.record(f)
+	}, nil
+}
+
+// coverageUnit is a command bash traces, spanning one or more lines. Bash
+// reports the last line of multi-line commands, so a trace of any line of
+// the command counts as a hit.
+type coverageUnit struct {
+	line int
+	end  int
+	fn   string
+}
+
+// shellFunc is a function defined in a library.
+type shellFunc struct {
+	name string
+	line int
+}
+
+// coverageUnits returns the commands of the script which are traced by bash
+// and the functions defined in it. Compound commands like if and while are
+// not traced themselves, only the commands they contain, except the headers
+// of case and for.
+func coverageUnits(path string) ([]coverageUnit, []shellFunc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(f, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var units []coverageUnit
+	var funcs []shellFunc
+	var stack []syntax.Node
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+
+		fn := ""
+		for i := len(stack) - 1; i >= 0; i-- {
+			if decl, ok := stack[i].(*syntax.FuncDecl); ok {
+				fn = decl.Name.Value
+				break
+			}
+		}
+
+		switch n := node.(type) {
+		case *syntax.FuncDecl:
+			funcs = append(funcs, shellFunc{name: n.Name.Value, line: int(n.Pos().Line())})
+		case *syntax.Stmt:
+			switch n.Cmd.(type) {
+			case *syntax.CallExpr, *syntax.DeclClause, *syntax.TestClause, *syntax.ArithmCmd, *syntax.LetClause:
+				units = append(units, coverageUnit{line: int(n.Pos().Line()), end: int(n.End().Line()), fn: fn})
+			}
+		case *syntax.CaseClause:
+			units = append(units, coverageUnit{line: int(n.Case.Line()), end: int(n.In.Line()), fn: fn})
+		case *syntax.ForClause:
+			units = append(units, coverageUnit{line: int(n.ForPos.Line()), end: int(n.Loop.End().Line()), fn: fn})
+		}
+		return true
+	})
+	return units, funcs, nil
+}
+
+// fileCoverage is the line coverage of a library.
+type fileCoverage struct {
+	// path relative to the repository root.
+	path  string
+	lines []lineCoverage
+	funcs []funcCoverage
+}
+
+// lineCoverage is the number of times an executable line was traced.
+type lineCoverage struct {
+	line int
+	hits int
+	fn   string
+}
+
+// funcCoverage is the line coverage of a function. Hits is the number of
+// times its first line was executed, ie. approximately number of calls.
+type funcCoverage struct {
+	name    string
+	line    int
+	hits    int
+	lines   int
+	covered int
+}
+
+// profile maps the traces to executable lines of the traced files.
+func (c *coverage) profile() ([]fileCoverage, error) {
+	root, err := repoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var paths []string
+	for path := range c.hits {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var files []fileCoverage
+	for _, path := range paths {
+		fc, err := profileFile(path, c.hits[path])
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(root, path); err == nil {
+			fc.path = filepath.ToSlash(rel)
+		}
+		files = append(files, fc)
+	}
+	return files, nil
+}
+
+// profileFile computes line and function coverage of the file from the
+// traced lines.
+func profileFile(path string, traced map[int]int) (fileCoverage, error) {
+	units, funcs, err := coverageUnits(path)
+	if err != nil {
+		return fileCoverage{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	// A line is executable if a command starts on it.
+	lines := make(map[int]*lineCoverage)
+	for _, u := range units {
+		l, ok := lines[u.line]
+		if !ok {
+			l = &lineCoverage{line: u.line, fn: u.fn}
+			lines[u.line] = l
+		}
+		hits := 0
+		for line := u.line; line <= u.end; line++ {
+			hits += traced[line]
+		}
+		if hits > l.hits {
+			l.hits = hits
+		}
+	}
+
+	fc := fileCoverage{path: path}
+	for _, l := range lines {
+		fc.lines = append(fc.lines, *l)
+	}
+	sort.Slice(fc.lines, func(i, j int) bool { return fc.lines[i].line < fc.lines[j].line })
+
+	for _, f := range funcs {
+		c := funcCoverage{name: f.name, line: f.line}
+		first := true
+		for _, l := range fc.lines {
+			if l.fn != f.name {
+				continue
+			}
+			if first {
+				c.hits = l.hits
+				first = false
+			}
+			c.lines++
+			if l.hits > 0 {
+				c.covered++
+			}
+		}
+		fc.funcs = append(fc.funcs, c)
+	}
+	return fc, nil
+}
+
+// covered returns the number of executable lines and lines executed at
+// least once.
+func (fc fileCoverage) covered() (lines, covered int) {
+	for _, l := range fc.lines {
+		lines++
+		if l.hits > 0 {
+			covered++
+		}
+	}
+	return lines, covered
+}
+
+// writeLCOV writes the coverage in lcov tracefile format.
+func writeLCOV(w io.Writer, name string, files []fileCoverage) error {
+	bw := bufio.NewWriter(w)
+	for _, fc := range files {
+		fmt.Fprintf(bw, "TN:%s\n", name)
+		fmt.Fprintf(bw, "SF:%s\n", fc.path)
+		hit := 0
+		for _, f := range fc.funcs {
+			fmt.Fprintf(bw, "FN:%d,%s\n", f.line, f.name)
+		}
+		for _, f := range fc.funcs {
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", f.hits, f.name)
+			if f.hits > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "FNF:%d\n", len(fc.funcs))
+		fmt.Fprintf(bw, "FNH:%d\n", hit)
+		for _, l := range fc.lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", l.line, l.hits)
+		}
+		lines, covered := fc.covered()
+		fmt.Fprintf(bw, "LF:%d\n", lines)
+		fmt.Fprintf(bw, "LH:%d\n", covered)
+		fmt.Fprintf(bw, "end_of_record\n")
+	}
+	return bw.Flush()
+}
+
+// coberturaCoverage is the root element of a Cobertura report.
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      int                `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string            `xml:"name,attr"`
+	Filename   string            `xml:"filename,attr"`
+	LineRate   string            `xml:"line-rate,attr"`
+	BranchRate string            `xml:"branch-rate,attr"`
+	Complexity int               `xml:"complexity,attr"`
+	Methods    []coberturaMethod `xml:"methods>method"`
+	Lines      []coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name       string          `xml:"name,attr"`
+	Signature  string          `xml:"signature,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity int             `xml:"complexity,attr"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// rate formats the ratio for Cobertura reports.
+func rate(covered, total int) string {
+	if total == 0 {
+		return "1"
+	}
+	return strconv.FormatFloat(float64(covered)/float64(total), 'f', 4, 64)
+}
+
+// writeCobertura writes the coverage in Cobertura XML format, with a class
+// per library and a method per function.
+func writeCobertura(w io.Writer, name string, files []fileCoverage, timestamp time.Time) error {
+	root, err := repoRoot()
+	if err != nil {
+		return err
+	}
+
+	pkg := coberturaPackage{Name: name, BranchRate: "0"}
+	report := coberturaCoverage{
+		BranchRate: "0",
+		Version:    "libtest",
+		Timestamp:  timestamp.Unix(),
+		Sources:    []string{root},
+	}
+	for _, fc := range files {
+		lines, covered := fc.covered()
+		report.LinesValid += lines
+		report.LinesCovered += covered
+
+		class := coberturaClass{
+			Name:       strings.TrimSuffix(filepath.Base(fc.path), ".sh"),
+			Filename:   fc.path,
+			LineRate:   rate(covered, lines),
+			BranchRate: "0",
+		}
+		for _, f := range fc.funcs {
+			method := coberturaMethod{
+				Name:       f.name,
+				LineRate:   rate(f.covered, f.lines),
+				BranchRate: "0",
+			}
+			for _, l := range fc.lines {
+				if l.fn == f.name {
+					method.Lines = append(method.Lines, coberturaLine{Number: l.line, Hits: l.hits})
+				}
+			}
+			class.Methods = append(class.Methods, method)
+		}
+		for _, l := range fc.lines {
+			class.Lines = append(class.Lines, coberturaLine{Number: l.line, Hits: l.hits})
+		}
+		pkg.Classes = append(pkg.Classes, class)
+	}
+	pkg.LineRate = rate(report.LinesCovered, report.LinesValid)
+	report.LineRate = pkg.LineRate
+	report.Packages = []coberturaPackage{pkg}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// writeCoverageSummary writes a table of line coverage by function.
+func writeCoverageSummary(w io.Writer, files []fileCoverage) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "FILE\tFUNCTION\tLINES\tCOVERAGE\n")
+	for _, fc := range files {
+		for _, f := range fc.funcs {
+			fmt.Fprintf(tw, "%s:%d\t%s\t%d/%d\t%s\n", fc.path, f.line, f.name, f.covered, f.lines, percent(f.covered, f.lines))
+		}
+		lines, covered := fc.covered()
+		fmt.Fprintf(tw, "%s\t(total)\t%d/%d\t%s\n", fc.path, covered, lines, percent(covered, lines))
+	}
+	return tw.Flush()
+}
+
+// percent formats the ratio as a percentage.
+func percent(covered, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(covered)/float64(total))
+}
+
+// writeCoverage writes lcov, Cobertura and summary reports of the package
+// to the directory, named after the package path relative to the
+// repository root, like dl.lcov, dl.cobertura.xml and dl.txt.
+func (c *coverage) writeCoverage(dir string, summary io.Writer) error {
+	files, err := c.profile()
+	if err != nil {
+		return err
+	}
+	name, err := coveragePackageName()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	write := func(ext string, fn func(io.Writer) error) error {
+		f, err := os.Create(filepath.Join(dir, name+ext))
+		if err != nil {
+			return err
+		}
+		if err := fn(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	if err := write(".lcov", func(w io.Writer) error { return writeLCOV(w, name, files) }); err != nil {
+		return err
+	}
+	if err := write(".cobertura.xml", func(w io.Writer) error { return writeCobertura(w, name, files, time.Now()) }); err != nil {
+		return err
+	}
+	if err := write(".txt", func(w io.Writer) error { return writeCoverageSummary(w, files) }); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(summary, "shell coverage of %s:\n", name)
+	return writeCoverageSummary(summary, files)
+}
+
+// coveragePackageName returns the path of the package being tested relative
+// to the repository root, with slashes replaced by dashes.
+func coveragePackageName() (string, error) {
+	root, err := repoRoot()
+	if err != nil {
+		return "", err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, wd)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "root", nil
+	}
+	return strings.ReplaceAll(filepath.ToSlash(rel), "/", "-"), nil
+}
+
+// ReportCoverage writes coverage reports of the shell invocations in the
+// package if coverage is enabled with CoverageEnv, and prints a summary by
+// function. It is meant to be called from TestMain with the exit code of the
+// tests, which is returned unless writing reports fails.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(libtest.ReportCoverage(m.Run()))
+//	}
+func ReportCoverage(code int) int {
+	dir, ok := coverageDir()
+	if !ok {
+		return code
+	}
+	if err := shellCoverage.writeCoverage(dir, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "libtest: failed to write coverage: %s\n", err)
+		if code == 0 {
+			return 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,160 @@
+package libtest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverageUnits(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.sh")
+	err := ioutil.WriteFile(script, []byte(`f() {
+    local a
+    a="$(echo x |
+        tr x y)"
+    case "$a" in
+    y)
+        echo \
+            multi
+        ;;
+    esac
+    if [ -n "$a" ]; then
+        for i in 1 2; do
+            : "$i"
+        done
+    fi
+}
+echo top
+`), 0644)
+	require.NoError(t, err)
+
+	units, funcs, err := coverageUnits(script)
+	require.NoError(t, err)
+
+	assert.Equal(t, []shellFunc{{name: "f", line: 1}}, funcs)
+	assert.ElementsMatch(t, []coverageUnit{
+		{line: 2, end: 2, fn: "f"},
+		{line: 3, end: 4, fn: "f"},
+		{line: 3, end: 3, fn: "f"},
+		{line: 4, end: 4, fn: "f"},
+		{line: 5, end: 5, fn: "f"},
+		{line: 7, end: 8, fn: "f"},
+		{line: 11, end: 11, fn: "f"},
+		{line: 12, end: 12, fn: "f"},
+		{line: 13, end: 13, fn: "f"},
+		{line: 17, end: 17},
+	}, units)
+}
+
+// withCoverage enables coverage for the test and collects traces in a new
+// collector.
+func withCoverage(t *testing.T) *coverage {
+	t.Helper()
+
+	t.Setenv(CoverageEnv, t.TempDir())
+	saved := shellCoverage
+	shellCoverage = newCoverage()
+	t.Cleanup(func() { shellCoverage = saved })
+	return shellCoverage
+}
+
+func TestCoverage(t *testing.T) {
+	c := withCoverage(t)
+
+	r := Run(t, "bash", []string{"math"}, `math__is_integer 5; math__is_integer 7`)
+	require.Equal(t, 0, r.ExitCode)
+	assert.Empty(t, r.Stderr, "traces must not be written to stderr")
+
+	files, err := c.profile()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	fc := files[0]
+	assert.Equal(t, "utils/math.sh", fc.path)
+	assert.Equal(t, []lineCoverage{
+		{line: 3, hits: 2, fn: "math__is_integer"},
+		{line: 5, hits: 0, fn: "math__is_integer"},
+		{line: 8, hits: 2, fn: "math__is_integer"},
+	}, fc.lines)
+	assert.Equal(t, []funcCoverage{
+		{name: "math__is_integer", line: 2, hits: 2, lines: 3, covered: 2},
+	}, fc.funcs)
+
+	t.Run("lcov", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeLCOV(&b, "utils", files))
+		assert.Equal(t, strings.Join([]string{
+			"TN:utils",
+			"SF:utils/math.sh",
+			"FN:2,math__is_integer",
+			"FNDA:2,math__is_integer",
+			"FNF:1",
+			"FNH:1",
+			"DA:3,2",
+			"DA:5,0",
+			"DA:8,2",
+			"LF:3",
+			"LH:2",
+			"end_of_record",
+			"",
+		}, "\n"), b.String())
+	})
+
+	t.Run("cobertura", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeCobertura(&b, "utils", files, time.Unix(0, 0)))
+
+		var report coberturaCoverage
+		require.NoError(t, xml.Unmarshal(b.Bytes(), &report))
+		assert.Equal(t, 2, report.LinesCovered)
+		assert.Equal(t, 3, report.LinesValid)
+		assert.Equal(t, "0.6667", report.LineRate)
+		require.Len(t, report.Packages, 1)
+		require.Len(t, report.Packages[0].Classes, 1)
+
+		class := report.Packages[0].Classes[0]
+		assert.Equal(t, "utils/math.sh", class.Filename)
+		require.Len(t, class.Methods, 1)
+		assert.Equal(t, "math__is_integer", class.Methods[0].Name)
+		assert.Len(t, class.Lines, 3)
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, writeCoverageSummary(&b, files))
+		assert.Contains(t, b.String(), "utils/math.sh:2  math__is_integer  2/3    66.7%")
+		assert.Contains(t, b.String(), "utils/math.sh    (total)           2/3    66.7%")
+	})
+}
+
+func TestCoverageIgnoresOtherShells(t *testing.T) {
+	c := withCoverage(t)
+
+	r := Run(t, "dash", []string{"math"}, `math__is_integer 5`)
+	require.Equal(t, 0, r.ExitCode)
+	assert.Empty(t, r.Stderr)
+
+	files, err := c.profile()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestCoverageDisabled(t *testing.T) {
+	t.Setenv(CoverageEnv, "")
+	saved := shellCoverage
+	shellCoverage = newCoverage()
+	t.Cleanup(func() { shellCoverage = saved })
+
+	Run(t, "bash", []string{"math"}, `math__is_integer 5`)
+
+	files, err := shellCoverage.profile()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
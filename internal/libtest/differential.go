@@ -0,0 +1,119 @@
+package libtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+)
+
+// maxMatrixValue is the maximum length of values shown in a divergence
+// matrix, longer values are truncated.
+const maxMatrixValue = 60
+
+// Differential runs the script with every shell returned by Shells and fails
+// the test if the shells do not agree on stdout, stderr and exit code. All
+// the shells share the same hermetic environment. Divergences are reported
+// as a matrix with a row for each group of shells with the same result and
+// a column for each field they disagree on. Results are returned in the
+// order of the shells, for further checks.
+//
+//	libtest.Differential(t, []string{"dl"}, `__libdl_GOARM "$@"`, libtest.WithArgs("armv7l"))
+func Differential(t *testing.T, libs []string, script string, opts ...RunOption) []Result {
+	t.Helper()
+
+	cfg := runConfig{}
+	cfg.apply(t, opts)
+	if cfg.hermetic == nil {
+		cfg.hermetic = NewEnv(t)
+	}
+
+	var results []Result
+	for _, shell := range Shells(t) {
+		results = append(results, runT(t, shell.Name, libs, script, cfg))
+	}
+	if matrix := divergence(results); matrix != "" {
+		t.Errorf("shells disagree on %q with args %q:\n%s", script, cfg.args, matrix)
+	}
+	return results
+}
+
+// resultGroup is a set of shells with the same result.
+type resultGroup struct {
+	shells []string
+	result Result
+}
+
+// divergence returns a matrix of results grouped by shells, or an empty
+// string if all the results are the same.
+func divergence(results []Result) string {
+	var groups []*resultGroup
+	for _, r := range results {
+		var group *resultGroup
+		for _, g := range groups {
+			if g.result.Stdout == r.Stdout && g.result.Stderr == r.Stderr && g.result.ExitCode == r.ExitCode {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &resultGroup{result: r}
+			groups = append(groups, group)
+		}
+		group.shells = append(group.shells, r.Shell.Name)
+	}
+	if len(groups) < 2 {
+		return ""
+	}
+	// Majority first, so that odd ones out are easy to spot.
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].shells) > len(groups[j].shells)
+	})
+
+	columns := []struct {
+		name  string
+		value func(Result) string
+		quote bool
+	}{
+		{name: "EXIT", value: func(r Result) string { return fmt.Sprint(r.ExitCode) }},
+		{name: "STDOUT", value: func(r Result) string { return r.Stdout }, quote: true},
+		{name: "STDERR", value: func(r Result) string { return r.Stderr }, quote: true},
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	header := []string{"SHELLS"}
+	var diverging []int
+	for i, c := range columns {
+		for _, g := range groups[1:] {
+			if c.value(g.result) != c.value(groups[0].result) {
+				header = append(header, c.name)
+				diverging = append(diverging, i)
+				break
+			}
+		}
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, g := range groups {
+		row := []string{strings.Join(g.shells, ", ")}
+		for _, i := range diverging {
+			v := columns[i].value(g.result)
+			if columns[i].quote {
+				v = matrixValue(v)
+			}
+			row = append(row, v)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// matrixValue quotes the value and truncates it to maxMatrixValue.
+func matrixValue(s string) string {
+	if len(s) > maxMatrixValue {
+		return fmt.Sprintf("%q...", s[:maxMatrixValue])
+	}
+	return fmt.Sprintf("%q", s)
+}
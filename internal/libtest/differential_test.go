@@ -0,0 +1,57 @@
+package libtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDivergence(t *testing.T) {
+	result := func(shell string, code int, stdout, stderr string) Result {
+		return Result{Shell: Shell{Name: shell}, ExitCode: code, Stdout: stdout, Stderr: stderr}
+	}
+
+	t.Run("agree", func(t *testing.T) {
+		assert.Empty(t, divergence([]Result{
+			result("bash", 0, "7", ""),
+			result("dash", 0, "7", ""),
+		}))
+	})
+
+	t.Run("single shell", func(t *testing.T) {
+		assert.Empty(t, divergence([]Result{result("bash", 1, "", "oops")}))
+	})
+
+	t.Run("only diverging columns", func(t *testing.T) {
+		got := divergence([]Result{
+			result("zsh", 1, "", "err"),
+			result("bash", 0, "7", "err"),
+			result("dash", 0, "7", "err"),
+		})
+		assert.Equal(t, strings.Join([]string{
+			"SHELLS      EXIT  STDOUT",
+			"bash, dash  0     \"7\"",
+			"zsh         1     \"\"",
+			"",
+		}, "\n"), got)
+	})
+
+	t.Run("long values are truncated", func(t *testing.T) {
+		long := strings.Repeat("a", maxMatrixValue)
+		got := divergence([]Result{
+			result("bash", 0, "", long+"b"),
+			result("dash", 0, "", long+"c"),
+		})
+		assert.Contains(t, got, `"`+long+`"...`)
+		assert.Equal(t, 3, strings.Count(got, "\n"), "long values which differ after the limit still diverge")
+	})
+}
+
+func TestDifferential(t *testing.T) {
+	results := Differential(t, []string{"math"}, `math__is_integer "$@" && printf '%s' "$HOME"`, WithArgs("12"))
+	for _, r := range results {
+		assert.Equal(t, 0, r.ExitCode)
+		assert.Equal(t, results[0].Stdout, r.Stdout, "shells share the environment")
+	}
+}
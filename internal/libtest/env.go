@@ -0,0 +1,215 @@
+package libtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Env is a hermetic environment for shell tests. It starts from a minimal
+// allowlist instead of the environment of the developer, so that variables
+// like LOG_FMT, NO_COLOR or TERM exported in the shell running the tests
+// cannot change the results. Variables are layered, later values override
+// earlier ones. Env is immutable, With returns a new Env.
+//
+//	env := libtest.NewEnv(t, "TZ=UTC").With("LOG_LVL=0")
+//	r := libtest.Run(t, "bash", []string{"logger"}, "log_info hello", libtest.WithHermeticEnv(env))
+type Env struct {
+	vars      []string
+	leakCheck bool
+}
+
+// NewEnv returns a minimal environment with PATH of the host, HOME pointing
+// to a temporary directory, GNUPGHOME inside it and LANG=C.UTF-8, layered
+// with the given variables in KEY=VALUE form.
+func NewEnv(t testing.TB, env ...string) *Env {
+	t.Helper()
+
+	home := t.TempDir()
+	e := &Env{
+		vars: []string{
+			"PATH=" + os.Getenv("PATH"),
+			"HOME=" + home,
+			"GNUPGHOME=" + filepath.Join(home, ".gnupg"),
+			"LANG=C.UTF-8",
+		},
+	}
+	return e.With(env...)
+}
+
+// With returns a copy of the environment layered with the variables in
+// KEY=VALUE form.
+func (e *Env) With(env ...string) *Env {
+	return &Env{
+		vars:      append(append([]string{}, e.vars...), env...),
+		leakCheck: e.leakCheck,
+	}
+}
+
+// WithLeakCheck returns a copy of the environment with leak check enabled.
+// With leak check, Run runs the script again with variables outside the
+// environment set to sentinel values, and fails the test if the output or
+// exit code changes, ie. the library depends on a variable which is not
+// declared. Candidates are variables referenced by the libraries and
+// variables exported on the host. Only use it with deterministic scripts.
+func (e *Env) WithLeakCheck() *Env {
+	c := e.With()
+	c.leakCheck = true
+	return c
+}
+
+// Get returns the value of the variable.
+func (e *Env) Get(key string) string {
+	v, _ := e.Lookup(key)
+	return v
+}
+
+// Lookup returns the value of the variable and whether it is set.
+func (e *Env) Lookup(key string) (string, bool) {
+	for i := len(e.vars) - 1; i >= 0; i-- {
+		if strings.HasPrefix(e.vars[i], key+"=") {
+			return e.vars[i][len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+// Environ returns the effective environment in KEY=VALUE form, sorted by
+// key. Each key appears only once, with its last value.
+func (e *Env) Environ() []string {
+	values := make(map[string]string)
+	for _, kv := range e.vars {
+		key := kv
+		if i := strings.Index(kv, "="); i >= 0 {
+			key = kv[:i]
+		}
+		values[key] = kv
+	}
+
+	environ := make([]string, 0, len(values))
+	for _, kv := range values {
+		environ = append(environ, kv)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// keys returns the names of the variables set in the environment.
+func (e *Env) keys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, kv := range e.Environ() {
+		keys[strings.SplitN(kv, "=", 2)[0]] = true
+	}
+	return keys
+}
+
+// WithHermeticEnv runs the shell with the environment instead of a default
+// one created with NewEnv. Variables set with WithEnv are layered on top.
+func WithHermeticEnv(env *Env) RunOption {
+	return func(c *runConfig) {
+		c.hermetic = env
+	}
+}
+
+// leakSentinels are the values variables are set to by the leak check. Some
+// variables are only checked for being set and others for specific values,
+// so more than one value is tried.
+var leakSentinels = []string{"1", "libtest-leak-check"}
+
+// leakIgnored are variables managed by the shell itself, which are never
+// treated as leaks.
+var leakIgnored = map[string]bool{
+	"_": true, "IFS": true, "OLDPWD": true, "OPTIND": true, "PPID": true,
+	"PS1": true, "PS2": true, "PS4": true, "PWD": true, "SHLVL": true,
+}
+
+var shellVariableRegex = regexp.MustCompile(`\$\{?([A-Z][A-Z0-9_]*)`)
+
+// leakCandidates returns the variables which are not part of the
+// environment, but are referenced by the libraries or exported on the host.
+func leakCandidates(libs []string, env *Env) ([]string, error) {
+	paths, err := resolveLibs(libs)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string]bool)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range shellVariableRegex.FindAllSubmatch(data, -1) {
+			candidates[string(m[1])] = true
+		}
+	}
+	for _, kv := range os.Environ() {
+		candidates[strings.SplitN(kv, "=", 2)[0]] = true
+	}
+
+	declared := env.keys()
+	var names []string
+	for name := range candidates {
+		if !declared[name] && !leakIgnored[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// checkLeaks runs the script with the candidate variables set to sentinel
+// values and returns the variables which change the result. Variables are
+// only checked one by one if setting all of them changes the result.
+func checkLeaks(t *testing.T, shell string, libs []string, script string, cfg runConfig, want Result) ([]string, error) {
+	env := cfg.hermetic
+	candidates, err := leakCandidates(libs, env.With(cfg.env...))
+	if err != nil {
+		return nil, err
+	}
+
+	changed := func(vars []string, value string) (bool, error) {
+		c := cfg
+		var poison []string
+		for _, name := range vars {
+			poison = append(poison, name+"="+value)
+		}
+		c.hermetic = env.With(poison...)
+		got, err := run(t, shell, libs, script, c)
+		if err != nil {
+			return false, err
+		}
+		return got.Stdout != want.Stdout || got.Stderr != want.Stderr || got.ExitCode != want.ExitCode, nil
+	}
+
+	leaks := make(map[string]bool)
+	for _, value := range leakSentinels {
+		ok, err := changed(candidates, value)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for _, name := range candidates {
+			ok, err := changed([]string{name}, value)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				leaks[name] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range leaks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
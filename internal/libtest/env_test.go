@@ -0,0 +1,85 @@
+package libtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEnv(t *testing.T) {
+	env := NewEnv(t, "TZ=UTC", "LOG_LVL=0")
+
+	assert.Equal(t, os.Getenv("PATH"), env.Get("PATH"))
+	assert.Equal(t, "C.UTF-8", env.Get("LANG"))
+	assert.DirExists(t, env.Get("HOME"))
+	assert.NotEqual(t, os.Getenv("HOME"), env.Get("HOME"))
+	assert.Equal(t, filepath.Join(env.Get("HOME"), ".gnupg"), env.Get("GNUPGHOME"))
+	assert.Equal(t, "UTC", env.Get("TZ"))
+
+	_, ok := env.Lookup("TERM")
+	assert.False(t, ok)
+}
+
+func TestEnvWith(t *testing.T) {
+	base := NewEnv(t, "LOG_LVL=0")
+	layered := base.With("LOG_LVL=10", "NO_COLOR=1")
+
+	assert.Equal(t, "0", base.Get("LOG_LVL"))
+	assert.Equal(t, "10", layered.Get("LOG_LVL"))
+	assert.Equal(t, "1", layered.Get("NO_COLOR"))
+
+	environ := layered.Environ()
+	assert.Contains(t, environ, "LOG_LVL=10")
+	assert.NotContains(t, environ, "LOG_LVL=0")
+	assert.Len(t, environ, 6)
+}
+
+func TestRunHermeticEnv(t *testing.T) {
+	t.Setenv("LIBTEST_HOST_VARIABLE", "leaked")
+
+	r := Run(t, "sh", nil, `printf '%s|%s|%s' "${LIBTEST_HOST_VARIABLE:-unset}" "$LANG" "$FOO"`,
+		WithHermeticEnv(NewEnv(t, "FOO=bar")),
+	)
+	assert.Equal(t, "unset|C.UTF-8|bar", r.Stdout)
+
+	// default environment is hermetic too.
+	r = Run(t, "sh", nil, `printf '%s' "${LIBTEST_HOST_VARIABLE:-unset}"`, WithEnv("FOO=baz"))
+	assert.Equal(t, "unset", r.Stdout)
+}
+
+func TestCheckLeaks(t *testing.T) {
+	lib := filepath.Join(t.TempDir(), "leaky.sh")
+	require.NoError(t, ioutil.WriteFile(lib, []byte(`
+leaky() {
+	if [ -n "${LIBTEST_LEAKY_FLAG}" ]; then
+		printf 'flag'
+	fi
+	printf '%s' "${LIBTEST_DECLARED:-}"
+}
+`), 0644))
+
+	tt := []struct {
+		name  string
+		env   *Env
+		leaks []string
+	}{
+		{name: "leaky", env: NewEnv(t, "LIBTEST_DECLARED=x"), leaks: []string{"LIBTEST_LEAKY_FLAG"}},
+		{name: "declared", env: NewEnv(t, "LIBTEST_DECLARED=x", "LIBTEST_LEAKY_FLAG=")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := runConfig{hermetic: tc.env}
+			r, err := run(t, "sh", []string{lib}, "leaky", cfg)
+			require.NoError(t, err)
+
+			leaks, err := checkLeaks(t, "sh", []string{lib}, "leaky", cfg, r)
+			require.NoError(t, err)
+			assert.Equal(t, tc.leaks, leaks)
+		})
+	}
+}
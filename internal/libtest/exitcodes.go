@@ -0,0 +1,394 @@
+package libtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// shellExitCodes are exit codes set by the shell itself, like 127 for
+// commands which are not found. They can be documented without being
+// returned by the library.
+var shellExitCodes = map[int]bool{126: true, 127: true}
+
+// NotReturnedMarker is the prefix of a comment marking a case of the
+// explaining function as never returned, like codes which are no longer used
+// but still explained for callers handling them. The reason follows it.
+//
+//	# Not returned: duplicate of 42.
+//	18) log_error "Signature specified is invalid." ;;
+const NotReturnedMarker = "Not returned:"
+
+// ExitCodeCatalog holds the exit codes of a library, as returned by its
+// functions and as documented by its error explaining function.
+type ExitCodeCatalog struct {
+	// Library is the path of the library relative to the repository root.
+	Library string `json:"library"`
+	// Explain is the name of the function documenting the exit codes.
+	Explain string     `json:"explain"`
+	Codes   []ExitCode `json:"codes"`
+}
+
+// ExitCode is an exit code of a library.
+type ExitCode struct {
+	Code int `json:"code"`
+	// Documented is true if the explaining function has a case for the code.
+	Documented bool `json:"documented"`
+	// NotReturned is true if the case of the code is marked as never
+	// returned, see NotReturnedMarker.
+	NotReturned bool `json:"not_returned,omitempty"`
+	// Messages are the messages printed by the explaining function.
+	Messages []string `json:"messages,omitempty"`
+	// ReturnedBy are the names of the functions returning the code.
+	ReturnedBy []string `json:"returned_by,omitempty"`
+	// Returns are the return statements with the code.
+	Returns []ExitCodeReturn `json:"-"`
+	// Line of the case of the code in the explaining function.
+	Line int `json:"-"`
+}
+
+// ExitCodeReturn is a return or exit statement with a literal code.
+type ExitCodeReturn struct {
+	// Func is the enclosing function, empty at top level.
+	Func string
+	Line int
+}
+
+// Undocumented returns the codes which are returned, but not documented.
+func (c *ExitCodeCatalog) Undocumented() []ExitCode {
+	var codes []ExitCode
+	for _, code := range c.Codes {
+		if !code.Documented {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// Unreachable returns the codes which are documented, but never returned,
+// except codes set by the shell itself, like 127, and codes marked as never
+// returned.
+func (c *ExitCodeCatalog) Unreachable() []ExitCode {
+	var codes []ExitCode
+	for _, code := range c.Codes {
+		if len(code.Returns) == 0 && !shellExitCodes[code.Code] && !code.NotReturned {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// JSON returns the catalog as indented JSON. Line numbers are omitted, so
+// that it only changes when codes or their messages change.
+func (c *ExitCodeCatalog) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// CheckExitCodes fails the test for every exit code returned by the library
+// which the explaining function does not document, and for every documented
+// code no function of the library returns. Only literal codes, like
+// `return 12` or `exit 12`, are taken into account.
+//
+// Problems of codes in knownBad are only logged, so that they can be fixed
+// separately. A known bad code without problems fails the test, so that the
+// list is kept up to date.
+//
+//	func TestExitCodes(t *testing.T) {
+//		libtest.CheckExitCodes(t, "dl", "shlib_explain_error", 8, 9)
+//	}
+func CheckExitCodes(t *testing.T, lib, explain string, knownBad ...int) *ExitCodeCatalog {
+	t.Helper()
+
+	catalog, err := ParseExitCodes(lib, explain)
+	if err != nil {
+		t.Fatalf("failed to parse exit codes of %s: %s", lib, err)
+	}
+	errs, known := catalog.problems(knownBad)
+	for _, msg := range known {
+		t.Logf("%s (known bad)", msg)
+	}
+	for _, msg := range errs {
+		t.Error(msg)
+	}
+	return catalog
+}
+
+// problems returns the problems of the exit codes, except those of known
+// bad codes, which are returned separately.
+func (c *ExitCodeCatalog) problems(knownBad []int) (errs, known []string) {
+	bad := make(map[int]bool)
+	for _, code := range knownBad {
+		bad[code] = true
+	}
+	found := make(map[int]bool)
+	report := func(code int, msg string) {
+		if bad[code] {
+			found[code] = true
+			known = append(known, msg)
+		} else {
+			errs = append(errs, msg)
+		}
+	}
+
+	for _, code := range c.Undocumented() {
+		for _, r := range code.Returns {
+			report(code.Code, fmt.Sprintf("%s:%d: exit code %d is not documented by %s (in %s)",
+				c.Library, r.Line, code.Code, c.Explain, r.Func))
+		}
+	}
+	for _, code := range c.Unreachable() {
+		report(code.Code, fmt.Sprintf("%s:%d: exit code %d is documented by %s, but never returned",
+			c.Library, code.Line, code.Code, c.Explain))
+	}
+	for _, code := range c.Codes {
+		if !code.NotReturned {
+			continue
+		}
+		for _, r := range code.Returns {
+			report(code.Code, fmt.Sprintf("%s:%d: exit code %d is marked as not returned by %s, but is returned (in %s)",
+				c.Library, r.Line, code.Code, c.Explain, r.Func))
+		}
+	}
+	for _, code := range knownBad {
+		if !found[code] {
+			errs = append(errs, fmt.Sprintf("%s: exit code %d is known bad, but has no problems", c.Library, code))
+		}
+	}
+	return errs, known
+}
+
+// ParseExitCodes returns the exit codes returned by the library and
+// documented by the case labels of its explaining function, like
+// shlib_explain_error of dl. Library can be a name or a path of a script.
+func ParseExitCodes(lib, explain string) (*ExitCodeCatalog, error) {
+	paths, err := resolveLibs([]string{lib})
+	if err != nil {
+		return nil, err
+	}
+	path := paths[len(paths)-1]
+
+	name := path
+	if root, err := repoRoot(); err == nil {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = filepath.ToSlash(rel)
+		}
+	}
+	return parseExitCodes(path, name, explain)
+}
+
+// parseExitCodes parses the script and builds its exit code catalog.
+func parseExitCodes(path, name, explain string) (*ExitCodeCatalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash), syntax.KeepComments(true)).Parse(f, path)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(map[int]*ExitCode)
+	get := func(n int) *ExitCode {
+		if codes[n] == nil {
+			codes[n] = &ExitCode{Code: n}
+		}
+		return codes[n]
+	}
+
+	found := false
+	var stack []syntax.Node
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, node)
+
+		switch node := node.(type) {
+		case *syntax.FuncDecl:
+			if node.Name.Value != explain {
+				return true
+			}
+			found = true
+			if err = parseExplainCases(node, get); err != nil {
+				return false
+			}
+			// Return codes of the explaining function itself are not
+			// exit codes of the library.
+			stack = stack[:len(stack)-1]
+			return false
+		case *syntax.CallExpr:
+			if len(node.Args) != 2 {
+				return true
+			}
+			switch node.Args[0].Lit() {
+			case "return", "exit":
+			default:
+				return true
+			}
+			n, convErr := strconv.Atoi(wordLiteral(node.Args[1]))
+			if convErr != nil {
+				return true
+			}
+			code := get(n)
+			code.Returns = append(code.Returns, ExitCodeReturn{
+				Func: enclosingFunc(stack),
+				Line: int(node.Pos().Line()),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("function %s not found in %s", explain, name)
+	}
+
+	catalog := &ExitCodeCatalog{Library: name, Explain: explain}
+	for _, code := range codes {
+		seen := make(map[string]bool)
+		for _, r := range code.Returns {
+			if !seen[r.Func] {
+				seen[r.Func] = true
+				code.ReturnedBy = append(code.ReturnedBy, r.Func)
+			}
+		}
+		sort.Strings(code.ReturnedBy)
+		catalog.Codes = append(catalog.Codes, *code)
+	}
+	sort.Slice(catalog.Codes, func(i, j int) bool {
+		return catalog.Codes[i].Code < catalog.Codes[j].Code
+	})
+	return catalog, nil
+}
+
+// enclosingFunc returns the name of the innermost function in the stack.
+func enclosingFunc(stack []syntax.Node) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if decl, ok := stack[i].(*syntax.FuncDecl); ok {
+			return decl.Name.Value
+		}
+	}
+	return ""
+}
+
+// parseExplainCases records the codes of the outermost case statement of
+// the explaining function, along with the messages printed for them.
+// Patterns which are not numbers, like `*`, are ignored.
+func parseExplainCases(decl *syntax.FuncDecl, get func(int) *ExitCode) error {
+	var clause *syntax.CaseClause
+	syntax.Walk(decl.Body, func(node syntax.Node) bool {
+		if c, ok := node.(*syntax.CaseClause); ok && clause == nil {
+			clause = c
+		}
+		return clause == nil
+	})
+	if clause == nil {
+		return fmt.Errorf("function %s has no case statement", decl.Name.Value)
+	}
+
+	for _, item := range clause.Items {
+		messages := explainMessages(item)
+		notReturned := false
+		for _, comment := range item.Comments {
+			if strings.HasPrefix(strings.TrimSpace(comment.Text), NotReturnedMarker) {
+				notReturned = true
+			}
+		}
+		for _, pattern := range item.Patterns {
+			n, err := strconv.Atoi(pattern.Lit())
+			if err != nil {
+				continue
+			}
+			code := get(n)
+			if code.Documented {
+				return fmt.Errorf("line %d: exit code %d is documented more than once", pattern.Pos().Line(), n)
+			}
+			code.Documented = true
+			code.NotReturned = notReturned
+			code.Line = int(pattern.Pos().Line())
+			code.Messages = messages
+		}
+	}
+	return nil
+}
+
+// errorPrefixRegex matches level prefixes of messages printed with printf,
+// like "[ERROR ] ".
+var errorPrefixRegex = regexp.MustCompile(`^\[[A-Z]+ *\] *`)
+
+// explainMessages returns messages printed by log_* functions or printf in
+// the case item.
+func explainMessages(item *syntax.CaseItem) []string {
+	var messages []string
+	for _, stmt := range item.Stmts {
+		syntax.Walk(stmt, func(node syntax.Node) bool {
+			call, ok := node.(*syntax.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			cmd := call.Args[0].Lit()
+			switch {
+			case cmd == "printf":
+				msg := strings.TrimSuffix(wordSource(call.Args[1]), `\n`)
+				messages = append(messages, errorPrefixRegex.ReplaceAllString(msg, ""))
+			case strings.HasPrefix(cmd, "log_"):
+				var args []string
+				for _, arg := range call.Args[1:] {
+					args = append(args, wordSource(arg))
+				}
+				messages = append(messages, strings.Join(args, " "))
+			}
+			return true
+		})
+	}
+	return messages
+}
+
+// wordLiteral returns the value of a word which is a literal, optionally
+// quoted, like 12 or "12". It returns an empty string for other words.
+func wordLiteral(w *syntax.Word) string {
+	if len(w.Parts) != 1 {
+		return ""
+	}
+	switch part := w.Parts[0].(type) {
+	case *syntax.Lit:
+		return part.Value
+	case *syntax.SglQuoted:
+		return part.Value
+	case *syntax.DblQuoted:
+		if len(part.Parts) == 1 {
+			if lit, ok := part.Parts[0].(*syntax.Lit); ok {
+				return lit.Value
+			}
+		}
+	}
+	return ""
+}
+
+// wordSource returns the source of the word without surrounding quotes.
+func wordSource(w *syntax.Word) string {
+	var buf bytes.Buffer
+	syntax.NewPrinter().Print(&buf, w)
+	s := buf.String()
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package libtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exitCodesScript = `libfoo_explain() {
+    case ${1} in
+    0) ;;
+    1 | 127) printf "[ERROR ] Unhandled error\n" >&2 ;;
+    3) log_error "Invalid arguments - ${1}" ;;
+    4)
+        log_error "Not found"
+        case $(uname) in
+        Linux) log_error "Install it" ;;
+        esac
+        ;;
+    5) log_error "Never returned" ;;
+    *)
+        log_error "Unknown error"
+        return 1
+        ;;
+    esac
+    return "${1}"
+}
+
+__libfoo_run() {
+    if [ "$#" -lt 1 ]; then
+        return 3
+    fi
+    command -v foo || return "4"
+    return "${rc}"
+}
+
+__libfoo_other() {
+    [ -n "$1" ] || exit 6
+    return 0
+}
+`
+
+func TestParseExitCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sh")
+	require.NoError(t, ioutil.WriteFile(path, []byte(exitCodesScript), 0644))
+
+	catalog, err := parseExitCodes(path, "script.sh", "libfoo_explain")
+	require.NoError(t, err)
+
+	codes := make(map[int]ExitCode)
+	for _, code := range catalog.Codes {
+		codes[code.Code] = code
+	}
+	require.Len(t, codes, 7)
+
+	assert.Equal(t, []string{"Unhandled error"}, codes[1].Messages)
+	assert.Empty(t, codes[1].Returns, "returns of the explaining function are ignored")
+	assert.Equal(t, []string{"Invalid arguments - ${1}"}, codes[3].Messages)
+	assert.Equal(t, []ExitCodeReturn{{Func: "__libfoo_run", Line: 23}}, codes[3].Returns)
+	assert.Equal(t, []string{"Not found", "Install it"}, codes[4].Messages)
+	assert.Equal(t, []string{"__libfoo_run"}, codes[4].ReturnedBy)
+	assert.Equal(t, 6, codes[4].Line)
+	assert.Equal(t, []string{"__libfoo_other"}, codes[0].ReturnedBy)
+
+	var undocumented []int
+	for _, code := range catalog.Undocumented() {
+		undocumented = append(undocumented, code.Code)
+	}
+	assert.Equal(t, []int{6}, undocumented)
+
+	var unreachable []int
+	for _, code := range catalog.Unreachable() {
+		unreachable = append(unreachable, code.Code)
+	}
+	assert.Equal(t, []int{1, 5}, unreachable, "127 is set by the shell")
+
+	data, err := catalog.JSON()
+	require.NoError(t, err)
+	var decoded ExitCodeCatalog
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "libfoo_explain", decoded.Explain)
+	assert.Len(t, decoded.Codes, 7)
+	assert.NotContains(t, string(data), "Line")
+}
+
+func TestExitCodeProblems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sh")
+	require.NoError(t, ioutil.WriteFile(path, []byte(exitCodesScript), 0644))
+	catalog, err := parseExitCodes(path, "script.sh", "libfoo_explain")
+	require.NoError(t, err)
+
+	errs, known := catalog.problems(nil)
+	assert.Equal(t, []string{
+		"script.sh:30: exit code 6 is not documented by libfoo_explain (in __libfoo_other)",
+		"script.sh:4: exit code 1 is documented by libfoo_explain, but never returned",
+		"script.sh:12: exit code 5 is documented by libfoo_explain, but never returned",
+	}, errs)
+	assert.Empty(t, known)
+
+	errs, known = catalog.problems([]int{5, 6, 3})
+	assert.Equal(t, []string{
+		"script.sh:4: exit code 1 is documented by libfoo_explain, but never returned",
+		"script.sh: exit code 3 is known bad, but has no problems",
+	}, errs)
+	assert.Equal(t, []string{
+		"script.sh:30: exit code 6 is not documented by libfoo_explain (in __libfoo_other)",
+		"script.sh:12: exit code 5 is documented by libfoo_explain, but never returned",
+	}, known)
+}
+
+func TestExitCodesNotReturned(t *testing.T) {
+	script := `libfoo_explain() {
+    case ${1} in
+    # Errors
+    3) log_error "Returned" ;;
+    # Not returned: duplicate of 3.
+    4) log_error "Marked" ;;
+    # Not returned: still used.
+    5) log_error "Marked, but returned" ;;
+    esac
+}
+
+__libfoo_run() {
+    [ -n "$1" ] || return 3
+    return 5
+}
+`
+	path := filepath.Join(t.TempDir(), "script.sh")
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), 0644))
+	catalog, err := parseExitCodes(path, "script.sh", "libfoo_explain")
+	require.NoError(t, err)
+
+	require.Len(t, catalog.Codes, 3)
+	assert.False(t, catalog.Codes[0].NotReturned, "other comments are ignored")
+	assert.True(t, catalog.Codes[1].NotReturned)
+	assert.True(t, catalog.Codes[2].NotReturned)
+	assert.Empty(t, catalog.Unreachable())
+
+	errs, known := catalog.problems(nil)
+	assert.Equal(t, []string{
+		"script.sh:14: exit code 5 is marked as not returned by libfoo_explain, but is returned (in __libfoo_run)",
+	}, errs)
+	assert.Empty(t, known)
+
+	data, err := catalog.JSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"not_returned": true`)
+}
+
+func TestParseExitCodesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		err    string
+	}{
+		{name: "missing-function", script: "f() {\n    return 1\n}\n", err: "function libfoo_explain not found"},
+		{name: "no-case", script: "libfoo_explain() {\n    return 1\n}\n", err: "has no case statement"},
+		{name: "duplicate", script: "libfoo_explain() {\n    case $1 in\n    1) ;;\n    2 | 1) ;;\n    esac\n}\n", err: "exit code 1 is documented more than once"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script.sh")
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.script), 0644))
+			_, err := parseExitCodes(path, "script.sh", "libfoo_explain")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
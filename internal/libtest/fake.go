@@ -0,0 +1,343 @@
+package libtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeEnv is the environment variable which switches the test binary into
+// fake command helper mode. Its value is the directory of the fake command.
+const fakeEnv = "LIBTEST_FAKE_COMMAND_DIR"
+
+// FakeBehavior is the scripted behavior of a single invocation of a fake
+// command.
+type FakeBehavior struct {
+	// Args restricts the behavior to invocations whose arguments start with
+	// Args, like `--version`. Such behaviors are used for every matching
+	// invocation and take precedence over behaviors without Args.
+	Args []string `json:"args,omitempty"`
+	// Stdout is written to standard output of the fake command.
+	Stdout string `json:"stdout,omitempty"`
+	// Stderr is written to standard error of the fake command.
+	Stderr string `json:"stderr,omitempty"`
+	// ExitCode is the exit code of the fake command.
+	ExitCode int `json:"exit_code,omitempty"`
+	// ReadStdin records standard input of the fake command. It is opt-in, as
+	// most of the commands like curl never read standard input and reading it
+	// would consume input meant for the script.
+	ReadStdin bool `json:"read_stdin,omitempty"`
+}
+
+// FakeCall is a recorded invocation of a fake command.
+type FakeCall struct {
+	// Args are the arguments of the fake command, excluding the command name.
+	Args []string `json:"args"`
+	// Env is the environment of the fake command.
+	Env []string `json:"env"`
+	// Stdin is the standard input of the fake command, if recorded.
+	Stdin string `json:"stdin,omitempty"`
+	// Dir is the working directory of the fake command.
+	Dir string `json:"dir"`
+}
+
+// Getenv returns value of the environment variable of the call.
+func (c FakeCall) Getenv(key string) string {
+	for _, kv := range c.Env {
+		if strings.HasPrefix(kv, key+"=") {
+			return kv[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+// HasArgs returns true if the args appear in order and adjacent to each other
+// in the arguments of the call.
+//
+//	call.HasArgs("--retry", "5")
+func (c FakeCall) HasArgs(args ...string) bool {
+	for i := 0; i+len(args) <= len(c.Args); i++ {
+		match := true
+		for j, arg := range args {
+			if c.Args[i+j] != arg {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Fake is a fake command installed in a sandbox.
+type Fake struct {
+	sb   *Sandbox
+	name string
+	dir  string
+}
+
+// FakeCommand installs a fake command with the name into the bin directory
+// of the sandbox, replacing any host binary allowed with the same name. Fake
+// is backed by the test binary itself, re-executed in helper mode. Nth
+// invocation of the fake uses Nth behavior, and the last behavior is used
+// once all behaviors are exhausted. Without any behaviors, fake command
+// succeeds without any output. Behaviors with Args are not part of the
+// sequence and are used whenever arguments of the invocation match.
+//
+//	curl := sb.FakeCommand("curl", libtest.FakeBehavior{ExitCode: 22})
+//	...
+//	assert.True(t, curl.Calls()[0].HasArgs("--retry", "5"))
+func (sb *Sandbox) FakeCommand(name string, behaviors ...FakeBehavior) *Fake {
+	sb.t.Helper()
+
+	f := &Fake{
+		sb:   sb,
+		name: name,
+		dir:  filepath.Join(sb.dir, "fakes", name),
+	}
+
+	if err := os.MkdirAll(filepath.Join(f.dir, "calls"), 0755); err != nil {
+		sb.t.Fatalf("failed to create fake %s: %s", name, err)
+	}
+
+	data, err := json.Marshal(behaviors)
+	if err != nil {
+		sb.t.Fatalf("failed to encode behaviors of fake %s: %s", name, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(f.dir, "behaviors.json"), data, 0644); err != nil {
+		sb.t.Fatalf("failed to write behaviors of fake %s: %s", name, err)
+	}
+
+	sb.installStub(name, fakeEnv, f.dir)
+	return f
+}
+
+// installStub installs a stub with the name into the bin directory of the
+// sandbox, which re-executes the test binary with the helper mode variable
+// env set to dir. Path of the test binary is saved in dir, see helperDir.
+func (sb *Sandbox) installStub(name, env, dir string) {
+	sb.t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		sb.t.Fatalf("failed to find test binary: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "executable"), []byte(exe), 0644); err != nil {
+		sb.t.Fatalf("failed to write fake %s: %s", name, err)
+	}
+
+	stub := fmt.Sprintf("#!/bin/sh\n%s=%s exec %s \"$@\"\n", env, ShellQuote(dir), ShellQuote(exe))
+	path := filepath.Join(sb.bin, name)
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		sb.t.Fatalf("failed to replace %s with fake: %s", name, err)
+	}
+	if err := ioutil.WriteFile(path, []byte(stub), 0755); err != nil {
+		sb.t.Fatalf("failed to write fake %s: %s", name, err)
+	}
+}
+
+// FakeCommand creates a new sandbox without any host binaries and installs
+// a fake command with the name into it, see Sandbox.FakeCommand. Use Sandbox
+// of the fake to run shells with it.
+//
+//	curl := libtest.FakeCommand(t, "curl", libtest.FakeBehavior{ExitCode: 22})
+//	r := libtest.Run(t, "bash", []string{"dl"}, script, libtest.WithSandbox(curl.Sandbox()))
+func FakeCommand(t *testing.T, name string, behaviors ...FakeBehavior) *Fake {
+	t.Helper()
+	return NewSandbox(t).FakeCommand(name, behaviors...)
+}
+
+// Sandbox returns the sandbox in which the fake is installed.
+func (f *Fake) Sandbox() *Sandbox {
+	return f.sb
+}
+
+// Name returns name of the fake command.
+func (f *Fake) Name() string {
+	return f.name
+}
+
+// Calls returns the recorded invocations of the fake, in order.
+func (f *Fake) Calls() []FakeCall {
+	f.sb.t.Helper()
+
+	calls, err := readFakeCalls(f.dir)
+	if err != nil {
+		f.sb.t.Fatalf("failed to read calls of fake %s: %s", f.name, err)
+	}
+	return calls
+}
+
+// readFakeCalls reads calls recorded in the fake directory.
+func readFakeCalls(dir string) ([]FakeCall, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "calls", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// file names are zero padded, so that they sort in call order.
+	sort.Strings(files)
+
+	calls := make([]FakeCall, 0, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var call FakeCall
+		if err := json.Unmarshal(data, &call); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+// init runs the test binary as a fake command, when it's invoked by the
+// stubs installed by FakeCommand or FakeDate. Tests are never run in this
+// mode.
+func init() {
+	if dir, ok := helperDir(fakeEnv); ok {
+		os.Exit(runFake(dir, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if dir, ok := helperDir(dateEnv); ok {
+		os.Exit(runFakeDate(dir, os.Args[1:], os.Stdout, os.Stderr))
+	}
+}
+
+// helperDir returns the directory of the fake from the helper mode variable
+// env. Helper mode is only entered by the test binary which installed the
+// stub, so that other binaries importing this package, like the generators
+// in cmd, never run as fakes even if they inherit the variable.
+func helperDir(env string) (string, bool) {
+	dir := os.Getenv(env)
+	if dir == "" {
+		return "", false
+	}
+	installer, err := ioutil.ReadFile(filepath.Join(dir, "executable"))
+	if err != nil {
+		return "", false
+	}
+	exe, err := os.Executable()
+	if err != nil || exe != string(installer) {
+		return "", false
+	}
+	return dir, true
+}
+
+// runFake records the invocation and replays the scripted behavior. It
+// returns the exit code of the fake command.
+func runFake(dir string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var behaviors []FakeBehavior
+	data, err := ioutil.ReadFile(filepath.Join(dir, "behaviors.json"))
+	if err == nil {
+		err = json.Unmarshal(data, &behaviors)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "libtest: fake: failed to read behaviors: %s\n", err)
+		return 125
+	}
+
+	call := FakeCall{Args: args}
+	if call.Args == nil {
+		call.Args = []string{}
+	}
+	call.Dir, _ = os.Getwd()
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, fakeEnv+"=") {
+			call.Env = append(call.Env, kv)
+		}
+	}
+
+	f, _, err := reserveFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		fmt.Fprintf(stderr, "libtest: fake: failed to record call: %s\n", err)
+		return 125
+	}
+	defer f.Close()
+
+	var behavior FakeBehavior
+	var sequence []FakeBehavior
+	matched := false
+	for _, b := range behaviors {
+		if len(b.Args) == 0 {
+			sequence = append(sequence, b)
+			continue
+		}
+		if !matched && hasPrefix(args, b.Args) {
+			behavior, matched = b, true
+		}
+	}
+
+	if !matched && len(sequence) > 0 {
+		seq, n, err := reserveFile(filepath.Join(dir, "sequence"))
+		if err != nil {
+			fmt.Fprintf(stderr, "libtest: fake: failed to reserve sequence: %s\n", err)
+			return 125
+		}
+		seq.Close()
+
+		if n < len(sequence) {
+			behavior = sequence[n]
+		} else {
+			behavior = sequence[len(sequence)-1]
+		}
+	}
+
+	if behavior.ReadStdin {
+		in, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "libtest: fake: failed to read stdin: %s\n", err)
+			return 125
+		}
+		call.Stdin = string(in)
+	}
+
+	if err := json.NewEncoder(f).Encode(call); err != nil {
+		fmt.Fprintf(stderr, "libtest: fake: failed to record call: %s\n", err)
+		return 125
+	}
+
+	io.WriteString(stdout, behavior.Stdout)
+	io.WriteString(stderr, behavior.Stderr)
+	return behavior.ExitCode
+}
+
+// reserveFile creates the next numbered file in the directory and returns it
+// along with its number. O_EXCL guarantees that concurrent invocations get
+// unique numbers.
+func reserveFile(dir string) (*os.File, int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, 0, err
+	}
+	for n := 0; ; n++ {
+		f, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%06d.json", n)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return f, n, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, 0, err
+		}
+	}
+}
+
+// hasPrefix returns true if args start with prefix.
+func hasPrefix(args, prefix []string) bool {
+	if len(prefix) > len(args) {
+		return false
+	}
+	for i := range prefix {
+		if args[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
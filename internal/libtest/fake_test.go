@@ -0,0 +1,116 @@
+package libtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeCommand(t *testing.T) {
+	sb := NewSandbox(t)
+	curl := sb.FakeCommand("curl",
+		FakeBehavior{Stdout: "first\n", Stderr: "warning\n", ExitCode: 22},
+		FakeBehavior{Stdout: "second\n"},
+	)
+
+	r := Run(t, "sh", nil, `curl --retry 5 "$1"; echo "rc=$?"; FOO=bar curl; curl`,
+		WithSandbox(sb),
+		WithArgs("https://example.com/a b"),
+	)
+
+	assert.Equal(t, 0, r.ExitCode)
+	assert.Equal(t, "first\nrc=22\nsecond\nsecond\n", r.Stdout)
+	assert.Equal(t, "warning\n", r.Stderr)
+
+	calls := curl.Calls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, []string{"--retry", "5", "https://example.com/a b"}, calls[0].Args)
+	assert.True(t, calls[0].HasArgs("--retry", "5"))
+	assert.False(t, calls[0].HasArgs("5", "--retry"))
+	assert.Equal(t, []string{}, calls[1].Args)
+	assert.Equal(t, "bar", calls[1].Getenv("FOO"))
+	assert.Equal(t, "", calls[2].Getenv("FOO"))
+	assert.Equal(t, "", calls[0].Getenv(fakeEnv))
+}
+
+func TestFakeCommandStdin(t *testing.T) {
+	sb := NewSandbox(t)
+	gpg := sb.FakeCommand("gpg", FakeBehavior{ReadStdin: true})
+
+	r := Run(t, "sh", nil, "gpg --verify", WithSandbox(sb), WithStdin(strings.NewReader("signed data")))
+	assert.Equal(t, 0, r.ExitCode)
+	assert.Empty(t, r.Stderr)
+
+	calls := gpg.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "signed data", calls[0].Stdin)
+}
+
+func TestFakeCommandReplacesAllowed(t *testing.T) {
+	sb := NewSandbox(t, "uname")
+	sb.FakeCommand("uname", FakeBehavior{Stdout: "riscv64\n"})
+
+	r := Run(t, "sh", nil, "uname -m", WithSandbox(sb))
+	assert.Equal(t, "riscv64\n", r.Stdout)
+}
+
+func TestFakeCommandArgs(t *testing.T) {
+	sb := NewSandbox(t)
+	sb.FakeCommand("gpgv",
+		FakeBehavior{Args: []string{"--version"}, Stdout: "gpgv (GnuPG) 2.2.40\n"},
+		FakeBehavior{ExitCode: 1},
+		FakeBehavior{ExitCode: 0},
+	)
+
+	r := Run(t, "sh", nil, `gpgv --version; gpgv a; echo "rc=$?"; gpgv --version; gpgv b; echo "rc=$?"`, WithSandbox(sb))
+	assert.Equal(t, "gpgv (GnuPG) 2.2.40\nrc=1\ngpgv (GnuPG) 2.2.40\nrc=0\n", r.Stdout)
+}
+
+func TestFakeCommandSandbox(t *testing.T) {
+	uname := FakeCommand(t, "uname", FakeBehavior{Stdout: "riscv64\n"})
+
+	r := Run(t, "sh", nil, "uname -m", WithSandbox(uname.Sandbox()))
+	assert.Equal(t, "riscv64\n", r.Stdout)
+	assert.Len(t, uname.Calls(), 1)
+}
+
+func TestHelperDir(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		executable string
+		ok         bool
+	}{
+		{name: "installed-by-this-binary", executable: exe, ok: true},
+		{name: "installed-by-other-binary", executable: filepath.Join(t.TempDir(), "gen-exit-codes")},
+		{name: "not-installed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tc.executable != "" {
+				require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "executable"), []byte(tc.executable), 0644))
+			}
+			t.Setenv(fakeEnv, dir)
+
+			got, ok := helperDir(fakeEnv)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, dir, got)
+			}
+		})
+	}
+
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv(fakeEnv, "")
+		_, ok := helperDir(fakeEnv)
+		assert.False(t, ok)
+	})
+}
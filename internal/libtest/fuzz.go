@@ -0,0 +1,62 @@
+//go:build go1.18
+// +build go1.18
+
+package libtest
+
+import (
+	"strings"
+	"testing"
+)
+
+// Oracle is a reference implementation of a shell function in Go. It
+// returns the expected stdout and exit code of the function for the input.
+type Oracle func(input string) (stdout string, code int)
+
+// FuzzFunction fuzzes the shell function with a single string argument and
+// fails if any of the shells disagrees with the oracle on stdout or exit
+// code, or writes anything to stderr. Input is passed as the first
+// positional parameter, so it needs no quoting. Inputs with NUL bytes are
+// skipped, as they cannot be passed to shells. Each shell runs as a Session,
+// so inputs do not spawn new shells.
+//
+// Seeds and the corpus in testdata/fuzz run as part of go test, new inputs
+// are generated with `go test -fuzz`, which saves failing inputs to the
+// corpus.
+//
+//	func FuzzGOOS(f *testing.F) {
+//		libtest.FuzzFunction(f, []string{"dl"}, "__libdl_GOOS", goosOracle, "Linux", "Darwin")
+//	}
+func FuzzFunction(f *testing.F, libs []string, fn string, oracle Oracle, seeds ...string) {
+	f.Helper()
+
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	var sessions []*Session
+	for _, name := range requestedShells() {
+		if _, ok := LookupShell(name); !ok {
+			continue
+		}
+		sessions = append(sessions, NewSession(f, name, libs))
+	}
+	if len(sessions) == 0 {
+		f.Skip("none of the requested shells are installed")
+	}
+
+	script := fn + ` "$@"`
+	f.Fuzz(func(t *testing.T, input string) {
+		if strings.IndexByte(input, 0) >= 0 {
+			t.Skip("shell arguments cannot contain NUL bytes")
+		}
+
+		stdout, code := oracle(input)
+		for _, s := range sessions {
+			r := s.Run(t, script, WithArgs(input))
+			if r.Stdout != stdout || r.ExitCode != code || r.Stderr != "" {
+				t.Errorf("%s: %s %q: got stdout %q, stderr %q, exit code %d, want stdout %q, exit code %d",
+					s.Shell().Name, fn, input, r.Stdout, r.Stderr, r.ExitCode, stdout, code)
+			}
+		}
+	})
+}
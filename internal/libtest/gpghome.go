@@ -0,0 +1,157 @@
+package libtest
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// GnuPGHome is an isolated GnuPG home directory for a test, so that
+// libraries using the default keyring never read or modify the keyring of
+// the user running the tests.
+//
+//	home := libtest.GPGHome(t).Import(f.Valid.PublicKey)
+//	r := libtest.Run(t, "bash", []string{"dl"}, `__libdl_gpg_verify "$@"`, libtest.WithGPGHome(home))
+//	home.AssertNotMutated(t)
+type GnuPGHome struct {
+	t        *testing.T
+	dir      string
+	snapshot string
+}
+
+// GPGHome creates an empty GnuPG home directory in a temporary directory,
+// which is removed when the test completes. Test is skipped if gpg is not
+// installed.
+func GPGHome(t *testing.T) *GnuPGHome {
+	t.Helper()
+
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skipf("gpg is not available on the host: %s", err)
+	}
+
+	// t.TempDir is created with 0700, which gpg requires for its home.
+	h := &GnuPGHome{t: t, dir: t.TempDir()}
+	h.snapshot = h.state()
+	return h
+}
+
+// Dir returns the GnuPG home directory.
+func (h *GnuPGHome) Dir() string {
+	return h.dir
+}
+
+// Import imports the keys into the keyring. Key files can be binary or
+// ASCII armored. State of the keyring after import is used by
+// AssertNotMutated.
+func (h *GnuPGHome) Import(keys ...string) *GnuPGHome {
+	h.t.Helper()
+
+	for _, key := range keys {
+		out, err := h.gpg("--import", key).CombinedOutput()
+		if err != nil {
+			h.t.Fatalf("failed to import %s: %s\n%s", key, err, out)
+		}
+	}
+	h.snapshot = h.state()
+	return h
+}
+
+// Fingerprints returns the fingerprints of the primary keys in the keyring.
+func (h *GnuPGHome) Fingerprints() []string {
+	h.t.Helper()
+
+	var fingerprints []string
+	primary := false
+	for _, line := range strings.Split(h.listKeys(), "\n") {
+		fields := strings.Split(line, ":")
+		switch {
+		case fields[0] == "pub":
+			primary = true
+		case fields[0] == "sub":
+			primary = false
+		case fields[0] == "fpr" && primary && len(fields) > 9:
+			fingerprints = append(fingerprints, fields[9])
+		}
+	}
+	return fingerprints
+}
+
+// AssertNotMutated asserts that keys and owner trust in the keyring are the
+// same as after the last Import.
+func (h *GnuPGHome) AssertNotMutated(t *testing.T) bool {
+	t.Helper()
+
+	if got, ok := h.mutated(); ok {
+		t.Errorf("gpg keyring %s was mutated\nbefore:\n%s\nafter:\n%s", h.dir, h.snapshot, got)
+		return false
+	}
+	return true
+}
+
+// mutated returns the current state of the keyring and whether it differs
+// from the snapshot.
+func (h *GnuPGHome) mutated() (string, bool) {
+	got := h.state()
+	return got, got != h.snapshot
+}
+
+// WithGPGHome sets GNUPGHOME of the shell to the GnuPG home directory.
+func WithGPGHome(h *GnuPGHome) RunOption {
+	return WithEnv("GNUPGHOME=" + h.Dir())
+}
+
+// gpg returns a gpg command operating on the home directory.
+func (h *GnuPGHome) gpg(args ...string) *exec.Cmd {
+	args = append([]string{"--homedir", h.dir, "--batch", "--no-tty"}, args...)
+	cmd := exec.Command("gpg", args...)
+	cmd.Env = NewEnv(h.t, "GNUPGHOME="+h.dir).Environ()
+	return cmd
+}
+
+// listKeys returns the keys in the keyring in colon format.
+func (h *GnuPGHome) listKeys() string {
+	h.t.Helper()
+
+	out, err := h.gpg("--with-colons", "--fixed-list-mode", "--list-keys").Output()
+	if err != nil {
+		// gpg returns an error for empty keyrings on some versions.
+		if len(bytes.TrimSpace(out)) == 0 {
+			return ""
+		}
+		h.t.Fatalf("failed to list keys in %s: %s", h.dir, err)
+	}
+
+	// tru records have timestamps of trust database checks.
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.HasPrefix(line, "tru:") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// state returns keys and owner trust of the keyring, which are compared by
+// AssertNotMutated. Files are not compared, as gpg updates its trust
+// database even for read only operations like --verify.
+func (h *GnuPGHome) state() string {
+	h.t.Helper()
+
+	// listing keys creates the trust database, which is required for
+	// exporting owner trust.
+	keys := h.listKeys()
+	out, err := h.gpg("--export-ownertrust").Output()
+	if err != nil {
+		h.t.Fatalf("failed to export owner trust of %s: %s", h.dir, err)
+	}
+
+	var trust []string
+	for _, line := range strings.Split(string(out), "\n") {
+		// skip comments, which include creation time of the export.
+		if line != "" && !strings.HasPrefix(line, "#") {
+			trust = append(trust, line)
+		}
+	}
+	return keys + strings.Join(trust, "\n")
+}
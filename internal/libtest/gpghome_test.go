@@ -0,0 +1,42 @@
+package libtest
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGPGHome(t *testing.T) {
+	f := NewOpenPGPFixtures(t, []byte("tprasadtp/shlibs\n"))
+	home := GPGHome(t)
+	assert.Empty(t, home.Fingerprints())
+
+	home.Import(f.Valid.PublicKey, f.Subkey.ArmoredPublicKey)
+	assert.ElementsMatch(t, []string{f.Valid.Fingerprint, f.Subkey.Fingerprint}, home.Fingerprints())
+
+	r := Run(t, "sh", nil, `gpg --batch --verify "$1" "$2"`,
+		WithGPGHome(home),
+		WithArgs(f.Valid.Signature, f.Target),
+	)
+	assert.Equal(t, 0, r.ExitCode, r.Stderr)
+	assert.True(t, home.AssertNotMutated(t))
+
+	// mutations are reported
+	out, err := exec.Command("gpg", "--homedir", home.Dir(), "--batch", "--import", f.Expired.PublicKey).CombinedOutput()
+	require.NoError(t, err, string(out))
+	_, mutated := home.mutated()
+	assert.True(t, mutated)
+}
+
+func TestGPGHomeIsolated(t *testing.T) {
+	a := GPGHome(t)
+	b := GPGHome(t)
+	assert.NotEqual(t, a.Dir(), b.Dir())
+
+	f := NewOpenPGPFixtures(t, []byte("tprasadtp/shlibs\n"))
+	a.Import(f.Valid.PublicKey)
+	assert.Len(t, a.Fingerprints(), 1)
+	assert.Empty(t, b.Fingerprints())
+}
@@ -0,0 +1,442 @@
+package libtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// LintFinding is a problem found by Lint.
+type LintFinding struct {
+	// Path of the library relative to the repository root.
+	Path string
+	Line int
+	Col  int
+	// Func is the function the problem is in, empty at top level.
+	Func    string
+	Message string
+}
+
+// String returns the finding in file:line:col: message form.
+func (f LintFinding) String() string {
+	s := fmt.Sprintf("%s:%d:%d: %s", f.Path, f.Line, f.Col, f.Message)
+	if f.Func != "" {
+		s += " (in " + f.Func + ")"
+	}
+	return s
+}
+
+// Lint statically analyzes the libraries and fails the test for each
+// problem found. Functions and global variables of the dependencies of the
+// libraries are taken into account, but only problems in the given libraries
+// are reported.
+//
+// It reports calls to undefined functions in the namespace of the libraries,
+// like `__libdl_foo` or `log_foo`, other commands are assumed to be external.
+// Locals which are read but never assigned or never read are reported, as
+// well as variables read in a function before they are first assigned in it.
+// Lower case variables which are read but never assigned by any library,
+// except as locals of other functions, are reported too. Upper case variables
+// are assumed to be set by the environment.
+//
+//	func TestLint(t *testing.T) {
+//		libtest.Lint(t, "dl")
+//	}
+func Lint(t *testing.T, libs ...string) {
+	t.Helper()
+
+	findings, err := lint(libs)
+	if err != nil {
+		t.Fatalf("failed to lint %v: %s", libs, err)
+	}
+	for _, f := range findings {
+		t.Errorf("%s", f)
+	}
+}
+
+// lint returns problems found in the libraries.
+func lint(libs []string) ([]LintFinding, error) {
+	all, err := resolveLibs(libs)
+	if err != nil {
+		return nil, err
+	}
+	// Only the given libraries are reported, which come last in dependency
+	// order.
+	reported := make(map[string]bool)
+	for _, lib := range libs {
+		paths, err := resolveLibs([]string{lib})
+		if err != nil {
+			return nil, err
+		}
+		reported[paths[len(paths)-1]] = true
+	}
+
+	root, err := repoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []*lintScript
+	for _, path := range all {
+		s, err := parseLintScript(path)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			s.name = filepath.ToSlash(rel)
+		}
+		scripts = append(scripts, s)
+	}
+
+	g := newLintGraph(scripts)
+	var findings []LintFinding
+	for _, s := range scripts {
+		if reported[s.path] {
+			findings = append(findings, g.check(s)...)
+		}
+	}
+	return findings, nil
+}
+
+// lintRef is a reference to a variable or function.
+type lintRef struct {
+	name string
+	pos  syntax.Pos
+	// loops are the loops enclosing the reference.
+	loops []syntax.Node
+}
+
+// lintScope holds the references of a function or the top level of a
+// script.
+type lintScope struct {
+	name    string
+	locals  []lintRef
+	assigns []lintRef
+	reads   []lintRef
+	calls   []lintRef
+}
+
+// lintScript is a parsed library.
+type lintScript struct {
+	path string
+	// name is path relative to the repository root.
+	name   string
+	funcs  []*lintScope
+	global *lintScope
+}
+
+var shellNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// readArgOptions are options of read which take an argument.
+var readArgOptions = map[string]bool{"-a": true, "-d": true, "-i": true, "-n": true, "-N": true, "-p": true, "-t": true, "-u": true}
+
+// parseLintScript parses the script and collects references by function.
+func parseLintScript(path string) (*lintScript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(f, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &lintScript{path: path, name: path, global: &lintScope{}}
+	var stack []syntax.Node
+
+	scope := func() *lintScope {
+		for i := len(stack) - 1; i >= 0; i-- {
+			if decl, ok := stack[i].(*syntax.FuncDecl); ok {
+				for _, fn := range s.funcs {
+					if fn.name == decl.Name.Value {
+						return fn
+					}
+				}
+			}
+		}
+		return s.global
+	}
+	ref := func(name string, pos syntax.Pos) lintRef {
+		r := lintRef{name: name, pos: pos}
+		for _, node := range stack {
+			switch node.(type) {
+			case *syntax.WhileClause, *syntax.ForClause:
+				r.loops = append(r.loops, node)
+			}
+		}
+		return r
+	}
+	assign := func(lit *syntax.Lit) {
+		if lit != nil && shellNameRegex.MatchString(lit.Value) {
+			sc := scope()
+			sc.assigns = append(sc.assigns, ref(lit.Value, lit.Pos()))
+		}
+	}
+	read := func(name string, pos syntax.Pos) {
+		if shellNameRegex.MatchString(name) {
+			sc := scope()
+			sc.reads = append(sc.reads, ref(name, pos))
+		}
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		var parent syntax.Node
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		stack = append(stack, node)
+
+		switch n := node.(type) {
+		case *syntax.FuncDecl:
+			s.funcs = append(s.funcs, &lintScope{name: n.Name.Value})
+		case *syntax.DeclClause:
+			local := n.Variant.Value == "local" || n.Variant.Value == "declare" || n.Variant.Value == "typeset"
+			for _, a := range n.Args {
+				if a.Name == nil {
+					continue
+				}
+				if local && scope() != s.global {
+					sc := scope()
+					sc.locals = append(sc.locals, ref(a.Name.Value, a.Name.Pos()))
+				}
+				if !a.Naked {
+					assign(a.Name)
+				}
+			}
+		case *syntax.Assign:
+			// names of declarations are handled above.
+			if _, ok := parent.(*syntax.DeclClause); ok {
+				return true
+			}
+			if !n.Naked {
+				assign(n.Name)
+			}
+			if n.Append {
+				read(n.Name.Value, n.Name.Pos())
+			}
+		case *syntax.CallExpr:
+			if len(n.Args) == 0 {
+				return true
+			}
+			name := n.Args[0].Lit()
+			if name == "" {
+				return true
+			}
+			sc := scope()
+			sc.calls = append(sc.calls, ref(name, n.Args[0].Pos()))
+			switch name {
+			case "read":
+				for i := 1; i < len(n.Args); i++ {
+					arg := n.Args[i].Lit()
+					if readArgOptions[arg] {
+						i++
+						continue
+					}
+					if arg != "" && !strings.HasPrefix(arg, "-") {
+						assign(n.Args[i].Parts[0].(*syntax.Lit))
+					}
+				}
+			case "getopts":
+				if len(n.Args) > 2 {
+					if lit, ok := n.Args[2].Parts[0].(*syntax.Lit); ok {
+						assign(lit)
+					}
+				}
+			case "printf":
+				if len(n.Args) > 2 && n.Args[1].Lit() == "-v" {
+					if lit, ok := n.Args[2].Parts[0].(*syntax.Lit); ok {
+						assign(lit)
+					}
+				}
+			}
+		case *syntax.WordIter:
+			assign(n.Name)
+		case *syntax.ParamExp:
+			if n.Param == nil {
+				return true
+			}
+			read(n.Param.Value, n.Param.Pos())
+			if n.Exp != nil && (n.Exp.Op.String() == ":=" || n.Exp.Op.String() == "=") {
+				assign(n.Param)
+			}
+		case *syntax.BinaryArithm:
+			if w, ok := n.X.(*syntax.Word); ok && strings.HasSuffix(n.Op.String(), "=") &&
+				n.Op.String() != "==" && n.Op.String() != "!=" && n.Op.String() != "<=" && n.Op.String() != ">=" {
+				if lit, ok := w.Parts[0].(*syntax.Lit); ok && len(w.Parts) == 1 {
+					assign(lit)
+				}
+			}
+		case *syntax.UnaryArithm:
+			if w, ok := n.X.(*syntax.Word); ok && (n.Op.String() == "++" || n.Op.String() == "--") {
+				if lit, ok := w.Parts[0].(*syntax.Lit); ok && len(w.Parts) == 1 {
+					assign(lit)
+				}
+			}
+		case *syntax.Word:
+			// Bare names in arithmetic expressions are variable reads.
+			switch parent.(type) {
+			case *syntax.ArithmExp, *syntax.ArithmCmd, *syntax.BinaryArithm, *syntax.UnaryArithm, *syntax.ParenArithm:
+				if lit := n.Lit(); lit != "" {
+					read(lit, n.Pos())
+				}
+			}
+		}
+		return true
+	})
+	return s, nil
+}
+
+// lintGraph holds functions and global variables defined by all the
+// libraries.
+type lintGraph struct {
+	funcs map[string]bool
+	// namespaces are the first words of function names, like libdl or log.
+	namespaces map[string]bool
+	// assigned are variables assigned anywhere, by function.
+	assigned map[string]map[string]bool
+	// globals are variables assigned without being declared local.
+	globals map[string]bool
+}
+
+func newLintGraph(scripts []*lintScript) *lintGraph {
+	g := &lintGraph{
+		funcs:      make(map[string]bool),
+		namespaces: make(map[string]bool),
+		assigned:   make(map[string]map[string]bool),
+		globals:    make(map[string]bool),
+	}
+	for _, s := range scripts {
+		for _, sc := range append(s.funcs, s.global) {
+			if sc != s.global {
+				g.funcs[sc.name] = true
+				g.namespaces[lintNamespace(sc.name)] = true
+			}
+			locals := make(map[string]bool)
+			for _, l := range sc.locals {
+				locals[l.name] = true
+			}
+			for _, a := range sc.assigns {
+				if !locals[a.name] {
+					g.globals[a.name] = true
+				}
+				if g.assigned[a.name] == nil {
+					g.assigned[a.name] = make(map[string]bool)
+				}
+				g.assigned[a.name][sc.name] = true
+			}
+		}
+	}
+	return g
+}
+
+// lintNamespace returns the first word of the function name, ignoring
+// leading underscores, like libdl for __libdl_GOOS.
+func lintNamespace(name string) string {
+	return strings.SplitN(strings.TrimLeft(name, "_"), "_", 2)[0]
+}
+
+// check returns the problems in the script.
+func (g *lintGraph) check(s *lintScript) []LintFinding {
+	var findings []LintFinding
+	report := func(sc *lintScope, pos syntax.Pos, format string, args ...interface{}) {
+		findings = append(findings, LintFinding{
+			Path:    s.name,
+			Line:    int(pos.Line()),
+			Col:     int(pos.Col()),
+			Func:    sc.name,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, sc := range append(append([]*lintScope{}, s.funcs...), s.global) {
+		for _, c := range sc.calls {
+			if !g.funcs[c.name] && g.namespaces[lintNamespace(c.name)] && strings.Contains(c.name, "_") {
+				report(sc, c.pos, "call to undefined function %s", c.name)
+			}
+		}
+
+		locals := make(map[string]lintRef)
+		for _, l := range sc.locals {
+			if _, ok := locals[l.name]; !ok {
+				locals[l.name] = l
+			}
+		}
+		assigned := make(map[string][]lintRef)
+		for _, a := range sc.assigns {
+			assigned[a.name] = append(assigned[a.name], a)
+		}
+		read := make(map[string]bool)
+		for _, r := range sc.reads {
+			read[r.name] = true
+		}
+
+		reported := make(map[string]bool)
+		for _, r := range sc.reads {
+			if reported[r.name] {
+				continue
+			}
+			_, local := locals[r.name]
+			switch {
+			case local && len(assigned[r.name]) == 0:
+				report(sc, r.pos, "local %s is read but never assigned", r.name)
+				reported[r.name] = true
+			case !local && !g.globals[r.name] && strings.ToUpper(r.name) != r.name:
+				report(sc, r.pos, "%s is read but never assigned", r.name)
+				reported[r.name] = true
+			case sc != s.global && len(assigned[r.name]) > 0 && len(g.assigned[r.name]) == 1 && readBeforeAssign(r, assigned[r.name]):
+				report(sc, r.pos, "%s is read before it is assigned", r.name)
+				reported[r.name] = true
+			}
+		}
+
+		var names []string
+		for name := range locals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !read[name] {
+				report(sc, locals[name].pos, "local %s is never read", name)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Col < findings[j].Col
+	})
+	return findings
+}
+
+// readBeforeAssign returns true if the read comes before all the
+// assignments, and is not in a loop with any of them, as the assignment can
+// happen in an earlier iteration.
+func readBeforeAssign(r lintRef, assigns []lintRef) bool {
+	for _, a := range assigns {
+		if a.pos.Offset() < r.pos.Offset() {
+			return false
+		}
+		for _, ra := range r.loops {
+			for _, aa := range a.loops {
+				if ra == aa {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package libtest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintUtils(t *testing.T) {
+	Lint(t, "math", "utils")
+}
+
+func TestLintFindings(t *testing.T) {
+	dir := t.TempDir()
+	dep := filepath.Join(dir, "dep.sh")
+	require.NoError(t, ioutil.WriteFile(dep, []byte(`__libdep_helper() {
+    dep_global="1"
+}
+`), 0644))
+
+	script := filepath.Join(dir, "script.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`__libfoo_ok() {
+    local a b="${1}" i
+    a="$(__libdep_helper)"
+    for i in 1 2; do
+        printf "%s %s %s %s" "$a" "$b" "$i" "$dep_global"
+    done
+    log_info "$HOME"
+}
+
+__libfoo_bad() {
+    local unused
+    local never_set
+    __libfoo_missing
+    libfoo_missing_too "$(__libfoo_ok)"
+    curl "$never_set" "$not_a_var" "${retries}"
+    retries=1
+    other_local=1
+}
+
+__libfoo_other() {
+    local other_local
+    other_local=2
+    echo "$other_local" "$other_local_typo"
+    count=0
+    while [ "$count" -lt 3 ]; do
+        echo "$((count + step))"
+        count=$((count + 1))
+        step=1
+    done
+}
+`), 0644))
+
+	findings, err := lint([]string{dep, script})
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		assert.Equal(t, script, f.Path)
+		got = append(got, f.String()[len(script):])
+	}
+	assert.Equal(t, []string{
+		":11:11: local unused is never read (in __libfoo_bad)",
+		":13:5: call to undefined function __libfoo_missing (in __libfoo_bad)",
+		":14:5: call to undefined function libfoo_missing_too (in __libfoo_bad)",
+		":15:12: local never_set is read but never assigned (in __libfoo_bad)",
+		":15:25: not_a_var is read but never assigned (in __libfoo_bad)",
+		":15:39: retries is read before it is assigned (in __libfoo_bad)",
+		":23:27: other_local_typo is read but never assigned (in __libfoo_other)",
+	}, got)
+}
+
+func TestLintOnlyReportsGivenLibraries(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte("__libbar_f() {\n    log_missing\n}\n"), 0644))
+
+	findings, err := lint([]string{"logger", script})
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, 2, findings[0].Line)
+	assert.Equal(t, "call to undefined function log_missing", findings[0].Message)
+	assert.Equal(t, "__libbar_f", findings[0].Func)
+}
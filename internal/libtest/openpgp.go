@@ -0,0 +1,387 @@
+package libtest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// OpenPGPFixtureTime is the creation time of all the generated OpenPGP keys
+// and signatures, so that fixtures are identical on every run.
+var OpenPGPFixtureTime = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// seedReader is a deterministic stream of bytes derived from a seed, used
+// as source of randomness for key generation. It is SHA-256 in counter mode
+// and is NOT suitable for anything other than generating test fixtures.
+type seedReader struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (r *seedReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			block := make([]byte, len(r.seed)+8)
+			copy(block, r.seed)
+			binary.BigEndian.PutUint64(block[len(r.seed):], r.counter)
+			sum := sha256.Sum256(block)
+			r.buf = sum[:]
+			r.counter++
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// openpgpKeyConfig holds options for NewOpenPGPKey.
+type openpgpKeyConfig struct {
+	expiry        time.Duration
+	revoked       bool
+	signingSubkey bool
+	selfSigHash   crypto.Hash
+}
+
+// OpenPGPKeyOption configures a generated OpenPGP key.
+type OpenPGPKeyOption func(*openpgpKeyConfig)
+
+// WithKeyExpiry sets the lifetime of the key, from OpenPGPFixtureTime.
+func WithKeyExpiry(d time.Duration) OpenPGPKeyOption {
+	return func(c *openpgpKeyConfig) {
+		c.expiry = d
+	}
+}
+
+// WithKeyRevoked revokes the key one hour after OpenPGPFixtureTime.
+// Signatures are still made before the revocation.
+func WithKeyRevoked() OpenPGPKeyOption {
+	return func(c *openpgpKeyConfig) {
+		c.revoked = true
+	}
+}
+
+// WithSigningSubkey adds a signing subkey to the key, which is then used for
+// making signatures instead of the primary key.
+func WithSigningSubkey() OpenPGPKeyOption {
+	return func(c *openpgpKeyConfig) {
+		c.signingSubkey = true
+	}
+}
+
+// WithSelfSigHash sets the hash algorithm used for self signatures and
+// binding signatures of the key, like crypto.SHA1 for legacy keys. Data
+// signatures always use SHA-256.
+func WithSelfSigHash(h crypto.Hash) OpenPGPKeyOption {
+	return func(c *openpgpKeyConfig) {
+		c.selfSigHash = h
+	}
+}
+
+// OpenPGPKey is a deterministically generated Ed25519 OpenPGP key for tests.
+// Same seed and options always generate the same key and signatures.
+type OpenPGPKey struct {
+	entity *openpgp.Entity
+}
+
+// NewOpenPGPKey generates an OpenPGP key from the seed. Seed is also used as
+// the name of the user ID of the key.
+func NewOpenPGPKey(seed string, opts ...OpenPGPKeyOption) (*OpenPGPKey, error) {
+	cfg := openpgpKeyConfig{selfSigHash: crypto.SHA256}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	config := &packet.Config{
+		Rand:        &seedReader{seed: []byte(seed)},
+		Time:        func() time.Time { return OpenPGPFixtureTime },
+		Algorithm:   packet.PubKeyAlgoEdDSA,
+		DefaultHash: crypto.SHA256,
+	}
+	if cfg.expiry > 0 {
+		config.KeyLifetimeSecs = uint32(cfg.expiry / time.Second)
+	}
+
+	entity, err := openpgp.NewEntity(seed, "shlibs test key", fmt.Sprintf("%s@shlibs.test", seed), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key %s: %w", seed, err)
+	}
+
+	if cfg.signingSubkey {
+		if err := entity.AddSigningSubkey(config); err != nil {
+			return nil, fmt.Errorf("failed to add signing subkey to %s: %w", seed, err)
+		}
+	}
+
+	// go-crypto refuses to generate keys preferring legacy hashes, so self
+	// signatures are re-signed with the requested hash.
+	if cfg.selfSigHash != crypto.SHA256 {
+		if err := resignOpenPGPKey(entity, cfg.selfSigHash, config); err != nil {
+			return nil, fmt.Errorf("failed to re-sign key %s: %w", seed, err)
+		}
+	}
+
+	if cfg.revoked {
+		config.Time = func() time.Time { return OpenPGPFixtureTime.Add(time.Hour) }
+		if err := entity.RevokeKey(packet.KeyCompromised, "test key revoked", config); err != nil {
+			return nil, fmt.Errorf("failed to revoke key %s: %w", seed, err)
+		}
+	}
+
+	return &OpenPGPKey{entity: entity}, nil
+}
+
+// resignOpenPGPKey re-signs user ID self signatures and subkey binding
+// signatures of the entity with the hash.
+func resignOpenPGPKey(e *openpgp.Entity, h crypto.Hash, config *packet.Config) error {
+	for _, ident := range e.Identities {
+		ident.SelfSignature.Hash = h
+		if err := ident.SelfSignature.SignUserId(ident.Name, e.PrimaryKey, e.PrivateKey, config); err != nil {
+			return err
+		}
+		ident.Signatures = []*packet.Signature{ident.SelfSignature}
+	}
+	for _, subkey := range e.Subkeys {
+		subkey.Sig.Hash = h
+		if err := subkey.Sig.SignKey(subkey.PublicKey, e.PrivateKey, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signConfig returns the config used for data signatures. Signatures are
+// made at OpenPGPFixtureTime, when the key is neither expired nor revoked.
+func (k *OpenPGPKey) signConfig() *packet.Config {
+	return &packet.Config{
+		Time:        func() time.Time { return OpenPGPFixtureTime },
+		DefaultHash: crypto.SHA256,
+	}
+}
+
+// signer returns the entity used for making data signatures. Signatures are
+// made before the key is revoked, so revocations are ignored.
+func (k *OpenPGPKey) signer() *openpgp.Entity {
+	e := *k.entity
+	e.Revocations = nil
+	return &e
+}
+
+// Fingerprint returns the fingerprint of the primary key in upper case hex.
+func (k *OpenPGPKey) Fingerprint() string {
+	return fmt.Sprintf("%X", k.entity.PrimaryKey.Fingerprint)
+}
+
+// PublicKey returns the binary public key, including subkeys and
+// revocations.
+func (k *OpenPGPKey) PublicKey() ([]byte, error) {
+	var b bytes.Buffer
+	if err := k.entity.Serialize(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ArmoredPublicKey returns the ASCII armored public key.
+func (k *OpenPGPKey) ArmoredPublicKey() ([]byte, error) {
+	return k.armor(openpgp.PublicKeyType, k.entity.Serialize)
+}
+
+// DetachSign returns a binary detached signature of data.
+func (k *OpenPGPKey) DetachSign(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	if err := openpgp.DetachSign(&b, k.signer(), bytes.NewReader(data), k.signConfig()); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ArmoredDetachSign returns an ASCII armored detached signature of data.
+func (k *OpenPGPKey) ArmoredDetachSign(data []byte) ([]byte, error) {
+	return k.armor(openpgp.SignatureType, func(w io.Writer) error {
+		return openpgp.DetachSign(w, k.signer(), bytes.NewReader(data), k.signConfig())
+	})
+}
+
+// ClearSign returns clearsigned data.
+func (k *OpenPGPKey) ClearSign(data []byte) ([]byte, error) {
+	config := k.signConfig()
+	key, ok := k.signer().SigningKey(config.Now())
+	if !ok {
+		return nil, fmt.Errorf("key %s has no valid signing key", k.Fingerprint())
+	}
+
+	var b bytes.Buffer
+	w, err := clearsign.Encode(&b, key.PrivateKey, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// armor armors the output of fn with the block type.
+func (k *OpenPGPKey) armor(blockType string, fn func(io.Writer) error) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, blockType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// OpenPGPFixture is a set of files for a single key. Paths are absolute.
+type OpenPGPFixture struct {
+	Fingerprint string
+	// PublicKey is the binary public key.
+	PublicKey string
+	// ArmoredPublicKey is the ASCII armored public key.
+	ArmoredPublicKey string
+	// Signature is the binary detached signature of the target.
+	Signature string
+	// ArmoredSignature is the ASCII armored detached signature of the target.
+	ArmoredSignature string
+	// ClearSigned is the clearsigned target.
+	ClearSigned string
+}
+
+// OpenPGPFixtures are OpenPGP keys and signatures of a target file covering
+// common failure modes.
+type OpenPGPFixtures struct {
+	// Target is the file which is signed.
+	Target string
+	// Valid is a valid key without any expiry.
+	Valid OpenPGPFixture
+	// Mismatch are signatures of Valid key over other data than target.
+	Mismatch OpenPGPFixture
+	// WrongKey is signed by a key other than Valid.
+	WrongKey OpenPGPFixture
+	// Expired is signed by a key which expired a day after it was created.
+	Expired OpenPGPFixture
+	// Revoked is signed by a revoked key.
+	Revoked OpenPGPFixture
+	// Subkey is signed by the signing subkey of the key.
+	Subkey OpenPGPFixture
+	// SHA1 is signed by a key with SHA-1 self signatures.
+	SHA1 OpenPGPFixture
+}
+
+// WriteOpenPGPFixtures generates OpenPGP fixtures for the target data and
+// writes them into the directory. Fixtures are deterministic, only the
+// directory they are written to differs.
+func WriteOpenPGPFixtures(dir string, target []byte) (OpenPGPFixtures, error) {
+	f := OpenPGPFixtures{Target: filepath.Join(dir, "target.txt")}
+	if err := ioutil.WriteFile(f.Target, target, 0644); err != nil {
+		return f, err
+	}
+
+	valid, err := NewOpenPGPKey("valid")
+	if err != nil {
+		return f, err
+	}
+
+	tests := []struct {
+		name    string
+		fixture *OpenPGPFixture
+		key     *OpenPGPKey
+		opts    []OpenPGPKeyOption
+		data    []byte
+	}{
+		{name: "valid", fixture: &f.Valid, key: valid, data: target},
+		{name: "mismatch", fixture: &f.Mismatch, key: valid, data: append([]byte("mismatch\n"), target...)},
+		{name: "wrong-key", fixture: &f.WrongKey, data: target},
+		{name: "expired", fixture: &f.Expired, opts: []OpenPGPKeyOption{WithKeyExpiry(24 * time.Hour)}, data: target},
+		{name: "revoked", fixture: &f.Revoked, opts: []OpenPGPKeyOption{WithKeyRevoked()}, data: target},
+		{name: "subkey", fixture: &f.Subkey, opts: []OpenPGPKeyOption{WithSigningSubkey()}, data: target},
+		{name: "sha1", fixture: &f.SHA1, opts: []OpenPGPKeyOption{WithSelfSigHash(crypto.SHA1)}, data: target},
+	}
+
+	for _, tc := range tests {
+		key := tc.key
+		if key == nil {
+			if key, err = NewOpenPGPKey(tc.name, tc.opts...); err != nil {
+				return f, err
+			}
+		}
+		if *tc.fixture, err = writeOpenPGPFixture(dir, tc.name, key, tc.data); err != nil {
+			return f, fmt.Errorf("failed to write %s fixtures: %w", tc.name, err)
+		}
+	}
+
+	// wrong-key signatures are verified against valid key.
+	f.WrongKey.Fingerprint = f.Valid.Fingerprint
+	f.WrongKey.PublicKey = f.Valid.PublicKey
+	f.WrongKey.ArmoredPublicKey = f.Valid.ArmoredPublicKey
+	return f, nil
+}
+
+// writeOpenPGPFixture writes key and signatures of the data with file names
+// prefixed with name.
+func writeOpenPGPFixture(dir, name string, key *OpenPGPKey, data []byte) (OpenPGPFixture, error) {
+	f := OpenPGPFixture{
+		Fingerprint:      key.Fingerprint(),
+		PublicKey:        filepath.Join(dir, name+".pub.gpg"),
+		ArmoredPublicKey: filepath.Join(dir, name+".pub.asc"),
+		Signature:        filepath.Join(dir, name+".sig.gpg"),
+		ArmoredSignature: filepath.Join(dir, name+".sig.asc"),
+		ClearSigned:      filepath.Join(dir, name+".clearsigned.asc"),
+	}
+
+	for _, file := range []struct {
+		path string
+		fn   func() ([]byte, error)
+	}{
+		{path: f.PublicKey, fn: key.PublicKey},
+		{path: f.ArmoredPublicKey, fn: key.ArmoredPublicKey},
+		{path: f.Signature, fn: func() ([]byte, error) { return key.DetachSign(data) }},
+		{path: f.ArmoredSignature, fn: func() ([]byte, error) { return key.ArmoredDetachSign(data) }},
+		{path: f.ClearSigned, fn: func() ([]byte, error) { return key.ClearSign(data) }},
+	} {
+		b, err := file.fn()
+		if err != nil {
+			return f, err
+		}
+		if err := ioutil.WriteFile(file.path, b, 0644); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// NewOpenPGPFixtures writes OpenPGP fixtures for the target data into a
+// temporary directory, which is removed when the test completes.
+func NewOpenPGPFixtures(t *testing.T, target []byte) OpenPGPFixtures {
+	t.Helper()
+
+	f, err := WriteOpenPGPFixtures(t.TempDir(), target)
+	if err != nil {
+		t.Fatalf("failed to generate OpenPGP fixtures: %s", err)
+	}
+	return f
+}
@@ -0,0 +1,161 @@
+package libtest
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOpenPGPKeyDeterministic(t *testing.T) {
+	a, err := NewOpenPGPKey("test", WithSigningSubkey())
+	require.NoError(t, err)
+	b, err := NewOpenPGPKey("test", WithSigningSubkey())
+	require.NoError(t, err)
+
+	assert.Equal(t, a.Fingerprint(), b.Fingerprint())
+
+	pa, err := a.PublicKey()
+	require.NoError(t, err)
+	pb, err := b.PublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, pa, pb)
+
+	sa, err := a.DetachSign([]byte("data"))
+	require.NoError(t, err)
+	sb, err := b.DetachSign([]byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, sa, sb)
+
+	other, err := NewOpenPGPKey("other")
+	require.NoError(t, err)
+	assert.NotEqual(t, a.Fingerprint(), other.Fingerprint())
+}
+
+func TestNewOpenPGPKeySelfSigHash(t *testing.T) {
+	k, err := NewOpenPGPKey("test", WithSelfSigHash(crypto.SHA1))
+	require.NoError(t, err)
+
+	for _, ident := range k.entity.Identities {
+		assert.Equal(t, crypto.SHA1, ident.SelfSignature.Hash)
+	}
+	for _, subkey := range k.entity.Subkeys {
+		assert.Equal(t, crypto.SHA1, subkey.Sig.Hash)
+	}
+}
+
+func TestWriteOpenPGPFixtures(t *testing.T) {
+	target := []byte("tprasadtp/shlibs\n")
+	f := NewOpenPGPFixtures(t, target)
+
+	tt := []struct {
+		name    string
+		fixture OpenPGPFixture
+		valid   bool
+		// clearsigned files carry their own plaintext, so mismatch
+		// signatures verify, but over different data.
+		clearSignValid bool
+	}{
+		{name: "valid", fixture: f.Valid, valid: true, clearSignValid: true},
+		{name: "mismatch", fixture: f.Mismatch, clearSignValid: true},
+		{name: "wrong-key", fixture: f.WrongKey},
+		{name: "subkey", fixture: f.Subkey, valid: true, clearSignValid: true},
+		{name: "sha1", fixture: f.SHA1, valid: true, clearSignValid: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			keyring := readKeyRing(t, tc.fixture.PublicKey)
+			armoredKeyring := readArmoredKeyRing(t, tc.fixture.ArmoredPublicKey)
+			assert.Equal(t, tc.fixture.Fingerprint, fmt.Sprintf("%X", keyring[0].PrimaryKey.Fingerprint))
+
+			for _, sig := range []string{tc.fixture.Signature, tc.fixture.ArmoredSignature} {
+				data, err := ioutil.ReadFile(sig)
+				require.NoError(t, err)
+
+				for _, k := range []openpgp.EntityList{keyring, armoredKeyring} {
+					var err error
+					if sig == tc.fixture.ArmoredSignature {
+						_, err = openpgp.CheckArmoredDetachedSignature(k, bytes.NewReader(target), bytes.NewReader(data), nil)
+					} else {
+						_, err = openpgp.CheckDetachedSignature(k, bytes.NewReader(target), bytes.NewReader(data), nil)
+					}
+					if tc.valid {
+						assert.NoError(t, err, sig)
+					} else {
+						assert.Error(t, err, sig)
+					}
+				}
+			}
+
+			data, err := ioutil.ReadFile(tc.fixture.ClearSigned)
+			require.NoError(t, err)
+			block, _ := clearsign.Decode(data)
+			require.NotNil(t, block)
+			_, err = block.VerifySignature(keyring, nil)
+			if tc.clearSignValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			if tc.valid {
+				assert.Equal(t, target, block.Plaintext)
+			}
+		})
+	}
+}
+
+func TestWriteOpenPGPFixturesDeterministic(t *testing.T) {
+	target := []byte("tprasadtp/shlibs\n")
+	a := NewOpenPGPFixtures(t, target)
+	b := NewOpenPGPFixtures(t, target)
+
+	for _, pair := range [][2]OpenPGPFixture{
+		{a.Valid, b.Valid},
+		{a.Expired, b.Expired},
+		{a.Revoked, b.Revoked},
+		{a.SHA1, b.SHA1},
+	} {
+		assert.Equal(t, pair[0].Fingerprint, pair[1].Fingerprint)
+		for _, files := range [][2]string{
+			{pair[0].PublicKey, pair[1].PublicKey},
+			{pair[0].ArmoredSignature, pair[1].ArmoredSignature},
+			{pair[0].ClearSigned, pair[1].ClearSigned},
+		} {
+			x, err := ioutil.ReadFile(files[0])
+			require.NoError(t, err)
+			y, err := ioutil.ReadFile(files[1])
+			require.NoError(t, err)
+			assert.Equal(t, x, y, files[0])
+		}
+	}
+}
+
+func readKeyRing(t *testing.T, path string) openpgp.EntityList {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	keyring, err := openpgp.ReadKeyRing(f)
+	require.NoError(t, err)
+	require.NotEmpty(t, keyring)
+	return keyring
+}
+
+func readArmoredKeyRing(t *testing.T, path string) openpgp.EntityList {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	keyring, err := openpgp.ReadArmoredKeyRing(f)
+	require.NoError(t, err)
+	require.NotEmpty(t, keyring)
+	return keyring
+}
@@ -0,0 +1,150 @@
+package libtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TimeoutEnv is the environment variable which sets the default timeout of
+// shell invocations, as a Go duration like `30s`. Zero disables the
+// timeout.
+const TimeoutEnv = "SHLIBS_TEST_TIMEOUT"
+
+// defaultTimeout is the timeout of shell invocations if TimeoutEnv is not
+// set.
+const defaultTimeout = 2 * time.Minute
+
+// DefaultTimeout returns the timeout of shell invocations which do not set
+// one with WithTimeout.
+func DefaultTimeout() (time.Duration, error) {
+	v := os.Getenv(TimeoutEnv)
+	if v == "" {
+		return defaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", TimeoutEnv, err)
+	}
+	return timeout, nil
+}
+
+// apply sets the default timeout and applies the options.
+func (c *runConfig) apply(t testing.TB, opts []RunOption) {
+	t.Helper()
+
+	timeout, err := DefaultTimeout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.timeout = timeout
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+// watchGroup kills the process group of the started process once the
+// timeout expires. Stop must be called once the command is waited for, it
+// kills processes left behind in the group and returns whether the timeout
+// expired along with the commands which were still running then.
+func watchGroup(p *os.Process, timeout time.Duration) (stop func() (bool, []string)) {
+	var mu sync.Mutex
+	var fired, stopped bool
+	var running []string
+
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if stopped {
+				return
+			}
+			fired = true
+			running = groupCommands(p.Pid)
+			killGroup(p)
+		})
+	}
+	return func() (bool, []string) {
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		killGroup(p)
+		return fired, running
+	}
+}
+
+// groupCommands returns command lines of the processes in the process group,
+// except the leader and its subshells. It returns nil if /proc is not
+// available.
+func groupCommands(pgid int) []string {
+	leader, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pgid), "cmdline"))
+	if err != nil {
+		return nil
+	}
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == pgid {
+			continue
+		}
+		stat, err := ioutil.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// Command name is in parentheses and can contain spaces, process
+		// group is the third field after it.
+		i := bytes.LastIndexByte(stat, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[i+1:]))
+		if len(fields) > 2 && fields[2] == strconv.Itoa(pgid) {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+
+	var commands []string
+	for _, pid := range pids {
+		cmdline, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+		if err != nil || len(cmdline) == 0 || bytes.Equal(cmdline, leader) {
+			continue
+		}
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+		commands = append(commands, strings.Join(args, " "))
+	}
+	return commands
+}
+
+// timeoutError describes a shell invocation which timed out, with the output
+// captured so far.
+func timeoutError(shell Shell, timeout time.Duration, script string, args, running []string, stdout, stderr string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: timed out after %s\nscript: %s\n", shell, timeout, script)
+	if len(args) > 0 {
+		fmt.Fprintf(&b, "args: %q\n", args)
+	}
+	if len(running) > 0 {
+		fmt.Fprintf(&b, "running: %s\n", strings.Join(running, "; "))
+	}
+	fmt.Fprintf(&b, "stdout: %s\nstderr: %s", stdout, stderr)
+	return errors.New(b.String())
+}
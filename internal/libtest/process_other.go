@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package libtest
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is not supported on this platform and does nothing.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killGroup kills the process. Its children are not killed on this platform.
+func killGroup(p *os.Process) {
+	p.Kill()
+}
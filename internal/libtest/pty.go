@@ -0,0 +1,155 @@
+package libtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/creack/pty"
+)
+
+// ttyConfig holds the options for RunPTY.
+type ttyConfig struct {
+	stdout bool
+	stderr bool
+	rows   uint16
+	cols   uint16
+}
+
+// WithTTY selects whether stdout and stderr of the shell are connected to a
+// pseudo-terminal or a pipe. Each stream gets its own pseudo-terminal, so
+// that they can be captured separately. Only used by RunPTY, which connects
+// both to a pseudo-terminal by default.
+//
+//	// stdout is a terminal, but stderr is redirected.
+//	r := libtest.RunPTY(t, "bash", []string{"logger"}, "log_info hello", libtest.WithTTY(true, false))
+func WithTTY(stdout, stderr bool) RunOption {
+	return func(c *runConfig) {
+		c.tty.stdout = stdout
+		c.tty.stderr = stderr
+	}
+}
+
+// WithWindowSize sets the window size of the pseudo-terminals. Only used by
+// RunPTY. Defaults to 24 rows and 80 columns.
+func WithWindowSize(rows, cols uint16) RunOption {
+	return func(c *runConfig) {
+		c.tty.rows = rows
+		c.tty.cols = cols
+	}
+}
+
+// RunPTY is like Run, but connects stdout and stderr of the shell to
+// pseudo-terminals, so that terminal detection like `[ -t 1 ]` succeeds.
+// Standard input is not a terminal. Output is captured as written to the
+// terminal, ie. with LF translated to CRLF. TERM is set to xterm-256color,
+// as bash sets it to dumb if unset, which can be overridden with WithEnv.
+// Test is skipped if the platform does not support pseudo-terminals.
+func RunPTY(t *testing.T, shell string, libs []string, script string, opts ...RunOption) Result {
+	t.Helper()
+
+	cfg := runConfig{
+		env: []string{"TERM=xterm-256color"},
+		tty: ttyConfig{stdout: true, stderr: true, rows: 24, cols: 80},
+	}
+	cfg.apply(t, opts)
+	cfg.useTTY = true
+	return runT(t, shell, libs, script, cfg)
+}
+
+// ttySession is a set of pseudo-terminals attached to a command.
+type ttySession struct {
+	ttys    []*os.File
+	ptys    []*os.File
+	copying sync.WaitGroup
+}
+
+// attachTTY connects stdout and/or stderr of the command to new
+// pseudo-terminals, whose output is copied to the writers.
+func attachTTY(cmd *exec.Cmd, cfg ttyConfig, stdout, stderr io.Writer) (*ttySession, error) {
+	s := &ttySession{}
+	for _, stream := range []struct {
+		enabled bool
+		target  *io.Writer
+		w       io.Writer
+	}{
+		{enabled: cfg.stdout, target: &cmd.Stdout, w: stdout},
+		{enabled: cfg.stderr, target: &cmd.Stderr, w: stderr},
+	} {
+		if !stream.enabled {
+			continue
+		}
+
+		p, tty, err := pty.Open()
+		if err != nil {
+			s.close()
+			if errors.Is(err, pty.ErrUnsupported) {
+				return nil, errPTYUnsupported
+			}
+			return nil, fmt.Errorf("failed to open pseudo-terminal: %w", err)
+		}
+		s.ptys = append(s.ptys, p)
+		s.ttys = append(s.ttys, tty)
+
+		if err := pty.Setsize(p, &pty.Winsize{Rows: cfg.rows, Cols: cfg.cols}); err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to set window size: %w", err)
+		}
+
+		*stream.target = tty
+		s.copying.Add(1)
+		go func(w io.Writer) {
+			defer s.copying.Done()
+			// Reading from pty returns an error (EIO on Linux) once all the
+			// processes holding the terminal have exited.
+			io.Copy(w, p)
+		}(stream.w)
+	}
+	return s, nil
+}
+
+// started closes the terminal side of the pseudo-terminals in the parent,
+// so that reads fail once the command exits.
+func (s *ttySession) started() {
+	if s == nil {
+		return
+	}
+	for _, tty := range s.ttys {
+		tty.Close()
+	}
+	s.ttys = nil
+}
+
+// wait waits for output to be copied and closes the pseudo-terminals.
+func (s *ttySession) wait() {
+	if s == nil {
+		return
+	}
+	s.started()
+	s.copying.Wait()
+	s.close()
+}
+
+func (s *ttySession) close() {
+	for _, f := range append(s.ttys, s.ptys...) {
+		f.Close()
+	}
+	s.ttys, s.ptys = nil, nil
+}
+
+// errPTYUnsupported is returned by run if pseudo-terminals are not
+// supported on the platform.
+var errPTYUnsupported = errors.New("pseudo-terminals are not supported")
+
+// attachTTYIf attaches pseudo-terminals to the command if requested by
+// RunPTY. It returns nil session otherwise.
+func attachTTYIf(cmd *exec.Cmd, cfg runConfig, stdout, stderr io.Writer) (*ttySession, error) {
+	if !cfg.useTTY {
+		return nil, nil
+	}
+	return attachTTY(cmd, cfg.tty, stdout, stderr)
+}
@@ -0,0 +1,46 @@
+package libtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPTY(t *testing.T) {
+	script := `[ -t 0 ] && echo in; [ -t 1 ] && echo out; [ -t 2 ] && echo err >&2; exit 3`
+
+	tt := []struct {
+		name   string
+		stdout bool
+		stderr bool
+		want   Result
+	}{
+		{name: "both", stdout: true, stderr: true, want: Result{Stdout: "out\r\n", Stderr: "err\r\n"}},
+		{name: "stdout", stdout: true, want: Result{Stdout: "out\r\n"}},
+		{name: "stderr", stderr: true, want: Result{Stderr: "err\r\n"}},
+		{name: "none"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := RunPTY(t, "sh", nil, script, WithTTY(tc.stdout, tc.stderr))
+			assert.Equal(t, tc.want.Stdout, r.Stdout)
+			assert.Equal(t, tc.want.Stderr, r.Stderr)
+			assert.Equal(t, 3, r.ExitCode)
+		})
+	}
+}
+
+func TestRunPTYWindowSize(t *testing.T) {
+	sb := NewSandbox(t, "stty")
+
+	r := RunPTY(t, "sh", nil, `stty size <&1`, WithSandbox(sb))
+	assert.Equal(t, "24 80\r\n", r.Stdout, r.Stderr)
+
+	r = RunPTY(t, "sh", nil, `stty size <&1`, WithSandbox(sb), WithWindowSize(50, 132))
+	assert.Equal(t, "50 132\r\n", r.Stdout, r.Stderr)
+}
+
+func TestRunPipesByDefault(t *testing.T) {
+	r := Run(t, "sh", nil, `[ -t 1 ] || echo pipe`)
+	assert.Equal(t, "pipe\n", r.Stdout)
+}
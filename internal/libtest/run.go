@@ -0,0 +1,359 @@
+package libtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Result is the result of a shell invocation.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+	// Shell used to run the script.
+	Shell Shell
+}
+
+// library is a shell library in this repository.
+type library struct {
+	// path of the library relative to the repository root.
+	path string
+	// deps are names of the libraries which must be sourced before this
+	// library.
+	deps []string
+}
+
+// libraries is the list of shell libraries known to Run.
+var libraries = map[string]library{
+	"logger": {path: "logger/logger.sh"},
+	"dl":     {path: "dl/dl.sh", deps: []string{"logger"}},
+	"math":   {path: "utils/math.sh"},
+	"utils":  {path: "utils/utils.sh"},
+}
+
+// runConfig holds the options for Run.
+type runConfig struct {
+	env      []string
+	hermetic *Env
+	dir      string
+	stdin    io.Reader
+	timeout  time.Duration
+	args     []string
+	// useTTY is set by RunPTY to connect the shell to pseudo-terminals.
+	useTTY bool
+	tty    ttyConfig
+	audit  *FSAudit
+}
+
+// RunOption configures a shell invocation.
+type RunOption func(*runConfig)
+
+// WithEnv adds environment variables in KEY=VALUE form to the shell
+// environment, overriding variables of the hermetic environment.
+func WithEnv(env ...string) RunOption {
+	return func(c *runConfig) {
+		c.env = append(c.env, env...)
+	}
+}
+
+// WithDir sets the working directory of the shell. Defaults to the package
+// directory.
+func WithDir(dir string) RunOption {
+	return func(c *runConfig) {
+		c.dir = dir
+	}
+}
+
+// WithStdin sets the standard input of the shell.
+func WithStdin(stdin io.Reader) RunOption {
+	return func(c *runConfig) {
+		c.stdin = stdin
+	}
+}
+
+// WithTimeout sets the maximum duration of the shell invocation, overriding
+// the default set by TimeoutEnv. Zero means no timeout. Shell and all the
+// processes it started are killed once the timeout expires.
+func WithTimeout(timeout time.Duration) RunOption {
+	return func(c *runConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithArgs sets the positional parameters ($1, $2 ...) of the script.
+// Arguments are passed as is, without any shell quoting or word splitting,
+// so `"$@"` in the script expands to exactly these arguments.
+func WithArgs(args ...string) RunOption {
+	return func(c *runConfig) {
+		c.args = append(c.args, args...)
+	}
+}
+
+// Run sources the given libraries along with their dependencies and runs the
+// script with the shell. Libraries can be names like "dl" and "logger" or
+// paths to shell scripts. Arguments set with WithArgs are available to the
+// script as positional parameters. Shell can be a name from the shell
+// registry, like "busybox-ash", or any executable in PATH. Shell runs in a
+// hermetic environment, see Env, and the effective environment is logged if
+// the test fails.
+//
+//	r := libtest.Run(t, "bash", []string{"dl"}, `__libdl_GOARM "$@"`, libtest.WithArgs("armv7l"))
+func Run(t *testing.T, shell string, libs []string, script string, opts ...RunOption) Result {
+	t.Helper()
+
+	cfg := runConfig{}
+	cfg.apply(t, opts)
+	return runT(t, shell, libs, script, cfg)
+}
+
+// runT runs the script, failing the test on errors. It logs the environment
+// on failure and runs the leak check if enabled.
+func runT(t *testing.T, shell string, libs []string, script string, cfg runConfig) Result {
+	t.Helper()
+
+	if cfg.hermetic == nil {
+		cfg.hermetic = NewEnv(t)
+	}
+	environ := cfg.hermetic.With(cfg.env...).Environ()
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("environment of %s:\n\t%s", shell, strings.Join(environ, "\n\t"))
+		}
+	})
+
+	var r Result
+	var err error
+	invoke := func() {
+		r, err = run(t, shell, libs, script, cfg)
+	}
+	if cfg.audit != nil {
+		cfg.audit.audit(t, cfg, invoke)
+	} else {
+		invoke()
+	}
+	if errors.Is(err, errPTYUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.hermetic.leakCheck {
+		leaks, err := checkLeaks(t, shell, libs, script, cfg, r)
+		if err != nil {
+			t.Fatalf("leak check failed: %s", err)
+		}
+		if len(leaks) > 0 {
+			t.Errorf("leak check: script depends on undeclared environment variables: %s", strings.Join(leaks, ", "))
+		}
+	}
+	return r
+}
+
+// run runs the script with the shell and returns the result. Non zero exit
+// codes are not treated as errors.
+func run(t *testing.T, name string, libs []string, script string, cfg runConfig) (Result, error) {
+	source, err := sourceLibs(libs)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to resolve libraries %v: %w", libs, err)
+	}
+
+	shell, ok := LookupShell(name)
+	if !ok {
+		return Result{}, fmt.Errorf("shell %s is not installed", name)
+	}
+
+	_, traced := coverageDir()
+	if traced {
+		source = coveragePrologue + source
+	}
+
+	args := append(append([]string{}, shell.Args...), "-c", source+script, "libtest")
+	args = append(args, cfg.args...)
+	cmd := exec.Command(shell.Path, args...)
+	setProcessGroup(cmd)
+	env := cfg.hermetic
+	if env == nil {
+		env = NewEnv(t)
+	}
+	cmd.Env = env.With(cfg.env...).Environ()
+	cmd.Dir = cfg.dir
+	cmd.Stdin = cfg.stdin
+	PrintCmdDebug(t, cmd)
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	ttys, err := attachTTYIf(cmd, cfg, &stdoutBuf, &stderrBuf)
+	if err != nil {
+		return Result{}, err
+	}
+
+	recordCoverage := func() error { return nil }
+	if traced {
+		if recordCoverage, err = traceCoverage(cmd, t.TempDir()); err != nil {
+			return Result{}, err
+		}
+	}
+
+	start := time.Now()
+	var timedOut bool
+	var running []string
+	err = cmd.Start()
+	if err == nil {
+		ttys.started()
+		stop := watchGroup(cmd.Process, cfg.timeout)
+		err = cmd.Wait()
+		timedOut, running = stop()
+	}
+	ttys.wait()
+	if err := recordCoverage(); err != nil {
+		return Result{}, fmt.Errorf("failed to record coverage: %w", err)
+	}
+	r := Result{
+		Stdout:   stdoutBuf.String(),
+		Stderr:   stderrBuf.String(),
+		Duration: time.Since(start),
+		Shell:    shell,
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case timedOut:
+		return r, timeoutError(shell, cfg.timeout, script, cfg.args, running, r.Stdout, r.Stderr)
+	case err == nil, errors.As(err, &exitErr):
+		r.ExitCode = cmd.ProcessState.ExitCode()
+		return r, nil
+	default:
+		return r, fmt.Errorf("failed to run %s: %w", shell, err)
+	}
+}
+
+// sourceLibs returns the shell commands to source the libraries and their
+// dependencies in dependency order, each library only once.
+func sourceLibs(libs []string) (string, error) {
+	paths, err := resolveLibs(libs)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, ". %s && ", ShellQuote(path))
+	}
+	return b.String(), nil
+}
+
+// LibraryPath returns the absolute path of the library, like dl or logger.
+func LibraryPath(lib string) (string, error) {
+	l, ok := libraries[lib]
+	if !ok {
+		return "", fmt.Errorf("unknown library %s", lib)
+	}
+	root, err := repoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, l.path), nil
+}
+
+// resolveLibs returns absolute paths of the libraries and their dependencies
+// in dependency order, each library only once.
+func resolveLibs(libs []string) ([]string, error) {
+	root, err := repoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+
+	var visit func(name string, stack []string) error
+	visit = func(name string, stack []string) error {
+		if seen[name] {
+			return nil
+		}
+		for _, s := range stack {
+			if s == name {
+				return fmt.Errorf("dependency cycle: %s", strings.Join(append(stack, name), " -> "))
+			}
+		}
+
+		var path string
+		if lib, ok := libraries[name]; ok {
+			for _, dep := range lib.deps {
+				if err := visit(dep, append(stack, name)); err != nil {
+					return err
+				}
+			}
+			path = filepath.Join(root, lib.path)
+		} else if strings.HasSuffix(name, ".sh") {
+			abs, err := filepath.Abs(name)
+			if err != nil {
+				return err
+			}
+			path = abs
+		} else {
+			return fmt.Errorf("unknown library: %s", name)
+		}
+
+		seen[name] = true
+		paths = append(paths, path)
+		return nil
+	}
+
+	for _, lib := range libs {
+		if err := visit(lib, nil); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+var (
+	repoRootOnce sync.Once
+	repoRootDir  string
+	repoRootErr  error
+)
+
+// repoRoot returns the root directory of the repository, ie. directory
+// containing go.mod.
+func repoRoot() (string, error) {
+	repoRootOnce.Do(func() {
+		dir, err := os.Getwd()
+		if err != nil {
+			repoRootErr = err
+			return
+		}
+		for {
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				repoRootDir = dir
+				return
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				repoRootErr = errors.New("go.mod not found")
+				return
+			}
+			dir = parent
+		}
+	})
+	return repoRootDir, repoRootErr
+}
+
+// ShellQuote quotes the string with single quotes, so that it is treated as
+// a single word by the shell.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,144 @@
+package libtest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		libs   []string
+		script string
+		opts   []RunOption
+		stdout string
+		stderr string
+		code   int
+	}{
+		"args with spaces and quotes": {
+			script: `printf '[%s]' "$@"`,
+			opts:   []RunOption{WithArgs("a b", `"q"`, "it's", "")},
+			stdout: `[a b]["q"][it's][]`,
+		},
+		"env": {
+			script: `printf '%s' "${LIBTEST_FOO}"`,
+			opts:   []RunOption{WithEnv("LIBTEST_FOO=bar baz")},
+			stdout: "bar baz",
+		},
+		"dir": {
+			script: `pwd`,
+			opts:   []RunOption{WithDir("/")},
+			stdout: "/\n",
+		},
+		"stdin": {
+			script: `cat`,
+			opts:   []RunOption{WithStdin(strings.NewReader("from stdin"))},
+			stdout: "from stdin",
+		},
+		"stderr and exit code": {
+			script: `printf 'oops' >&2; exit 3`,
+			stderr: "oops",
+			code:   3,
+		},
+		"library with dependencies": {
+			libs:   []string{"dl"},
+			script: `__libdl_is_function log_info && __libdl_GOARM "$@"`,
+			opts:   []RunOption{WithArgs("armv7l")},
+			stdout: "7",
+		},
+		"library path": {
+			libs:   []string{"../../utils/math.sh"},
+			script: `math__is_integer "$@"`,
+			opts:   []RunOption{WithArgs("12a")},
+			code:   1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := Run(t, "sh", tc.libs, tc.script, tc.opts...)
+			assert.Equal(t, tc.stdout, r.Stdout)
+			assert.Equal(t, tc.stderr, r.Stderr)
+			assert.Equal(t, tc.code, r.ExitCode)
+			assert.NotZero(t, r.Duration)
+		})
+	}
+}
+
+func TestSourceLibs(t *testing.T) {
+	root, err := repoRoot()
+	require.NoError(t, err)
+
+	source, err := sourceLibs([]string{"dl", "logger", "dl"})
+	require.NoError(t, err)
+	assert.Equal(t,
+		". '"+root+"/logger/logger.sh' && . '"+root+"/dl/dl.sh' && ",
+		source,
+	)
+
+	_, err = sourceLibs([]string{"no-such-lib"})
+	assert.Error(t, err)
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		r, err := run(t, "sh", nil, "printf partial; exec sleep 5", runConfig{timeout: 100 * time.Millisecond})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out after 100ms")
+		assert.Equal(t, "partial", r.Stdout)
+		assert.Less(t, int64(r.Duration), int64(5*time.Second))
+	})
+
+	t.Run("timeout kills process group", func(t *testing.T) {
+		r, err := run(t, "sh", nil, `sleep 7 & printf '%s' "$!"; sleep 6; wait`, runConfig{timeout: 200 * time.Millisecond})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out after 200ms")
+		assert.Contains(t, err.Error(), "script: sleep 7 &")
+		assert.Contains(t, err.Error(), "running: sleep 7; sleep 6")
+		assert.Less(t, int64(r.Duration), int64(5*time.Second))
+
+		// Background process is killed, though it may not be reaped yet.
+		stat, err := ioutil.ReadFile(filepath.Join("/proc", r.Stdout, "stat"))
+		if err == nil {
+			assert.Contains(t, string(stat), ") Z ", "background process must be killed")
+		}
+	})
+
+	t.Run("default timeout", func(t *testing.T) {
+		t.Setenv(TimeoutEnv, "")
+		timeout, err := DefaultTimeout()
+		require.NoError(t, err)
+		assert.Equal(t, defaultTimeout, timeout)
+
+		t.Setenv(TimeoutEnv, "150ms")
+		cfg := runConfig{}
+		cfg.apply(t, nil)
+		assert.Equal(t, 150*time.Millisecond, cfg.timeout)
+		cfg.apply(t, []RunOption{WithTimeout(0)})
+		assert.Zero(t, cfg.timeout)
+
+		t.Setenv(TimeoutEnv, "soon")
+		_, err = DefaultTimeout()
+		assert.Error(t, err)
+	})
+
+	t.Run("missing shell", func(t *testing.T) {
+		_, err := run(t, "no-such-shell", nil, "true", runConfig{})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown library", func(t *testing.T) {
+		_, err := run(t, "sh", []string{"no-such-lib"}, "true", runConfig{})
+		assert.Error(t, err)
+	})
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'it'\''s'`, ShellQuote("it's"))
+	assert.Equal(t, `''`, ShellQuote(""))
+}
@@ -0,0 +1,74 @@
+package libtest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Sandbox is a temporary directory with a bin directory which only exposes
+// an explicit allowlist of host binaries. Shells run with WithSandbox have
+// their PATH restricted to the bin directory, so that tests can control which
+// tools are available without building container images.
+//
+//	sb := libtest.NewSandbox(t, "curl", "shasum")
+//	r := libtest.Run(t, "bash", []string{"dl"}, "__libdl_has_wget", libtest.WithSandbox(sb))
+type Sandbox struct {
+	t   *testing.T
+	dir string
+	bin string
+}
+
+// NewSandbox creates a new sandbox exposing the given host binaries. Sandbox
+// is removed when the test and all its subtests complete.
+func NewSandbox(t *testing.T, tools ...string) *Sandbox {
+	t.Helper()
+
+	dir := t.TempDir()
+	sb := &Sandbox{
+		t:   t,
+		dir: dir,
+		bin: filepath.Join(dir, "bin"),
+	}
+
+	if err := os.Mkdir(sb.bin, 0755); err != nil {
+		t.Fatalf("failed to create sandbox bin directory: %s", err)
+	}
+
+	sb.Allow(tools...)
+	return sb
+}
+
+// Allow exposes the host binaries in the sandbox as symlinks. If a binary is
+// not available on the host, test is skipped, as the test cannot express its
+// intent without it.
+func (sb *Sandbox) Allow(tools ...string) {
+	sb.t.Helper()
+
+	for _, tool := range tools {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			sb.t.Skipf("%s is not available on the host: %s", tool, err)
+		}
+		if err := os.Symlink(path, filepath.Join(sb.bin, tool)); err != nil {
+			sb.t.Fatalf("failed to add %s to sandbox: %s", tool, err)
+		}
+	}
+}
+
+// Dir returns the root directory of the sandbox.
+func (sb *Sandbox) Dir() string {
+	return sb.dir
+}
+
+// BinDir returns the directory of the sandbox which is used as PATH.
+func (sb *Sandbox) BinDir() string {
+	return sb.bin
+}
+
+// WithSandbox restricts the PATH of the shell to the bin directory of the
+// sandbox.
+func WithSandbox(sb *Sandbox) RunOption {
+	return WithEnv("PATH=" + sb.BinDir())
+}
@@ -0,0 +1,43 @@
+package libtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandbox(t *testing.T) {
+	sb := NewSandbox(t, "cat")
+
+	target, err := os.Readlink(filepath.Join(sb.BinDir(), "cat"))
+	assert.NoError(t, err)
+	assert.Equal(t, "cat", filepath.Base(target))
+
+	tests := map[string]struct {
+		script string
+		stdout string
+		code   int
+	}{
+		"allowed":     {script: "command -v cat", stdout: filepath.Join(sb.BinDir(), "cat") + "\n"},
+		"not-allowed": {script: "command -v ls || echo missing", stdout: "missing\n"},
+		"run-allowed": {script: "echo hello | cat", stdout: "hello\n"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := Run(t, "sh", nil, tc.script, WithSandbox(sb))
+			assert.Equal(t, tc.code, r.ExitCode)
+			assert.Equal(t, tc.stdout, r.Stdout)
+		})
+	}
+}
+
+func TestSandboxMissingTool(t *testing.T) {
+	var skipped bool
+	t.Run("missing", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		NewSandbox(t, "no-such-tool")
+	})
+	assert.True(t, skipped)
+}
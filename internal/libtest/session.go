@@ -0,0 +1,346 @@
+package libtest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sessionDriver is the loop run by the shell of a session. Each call is sent
+// on stdin as lines of the script followed by the end marker. Script runs in
+// a subshell with stdin, stdout and stderr redirected to files in the session
+// directory, and the exit code is written to stdout after the exit marker.
+// Markers carry a random token, so that they never clash with scripts.
+const sessionDriver = `while IFS= read -r __libtest_line; do
+    __libtest_script=""
+    while [ "${__libtest_line}" != "__libtest_end_%[1]s" ]; do
+        __libtest_script="${__libtest_script}${__libtest_line}
+"
+        IFS= read -r __libtest_line || exit 0
+    done
+    ( eval "${__libtest_script}" ) <%[2]s/stdin >%[2]s/stdout 2>%[2]s/stderr
+    printf '__libtest_exit_%[1]s %%d\n' "$?"
+done
+`
+
+// Session is a long lived shell with libraries sourced once, which runs
+// scripts without spawning a new shell for each of them. Every script runs
+// in a subshell, so variables, functions, traps and working directory
+// changes of a script are not seen by the next one, but files written by
+// the scripts are shared. Calls are serialized, so a session can be shared
+// by parallel tests.
+//
+// Unlike Run, the hermetic environment is shared by all the calls of a
+// session and leak checks and pseudo-terminals are not supported.
+type Session struct {
+	shell Shell
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	// exits receives exit codes of the calls, it is closed when the shell
+	// exits.
+	exits chan int
+	// dir holds the stdin, stdout and stderr files of calls.
+	dir   string
+	token string
+
+	stderr bytes.Buffer
+
+	mu     sync.Mutex
+	broken error
+
+	recordCoverage func() error
+	waitOnce       sync.Once
+	waitErr        error
+	closeOnce      sync.Once
+	closeErr       error
+}
+
+// NewSession starts the shell and sources the given libraries along with
+// their dependencies. Options apply to the shell of the session, like
+// WithHermeticEnv, WithEnv and WithDir. Session is closed when the test
+// completes.
+//
+//	s := libtest.NewSession(t, "bash", []string{"dl"})
+//	r := s.Run(t, `shlib_explain_error "$@"`, libtest.WithArgs("12"))
+func NewSession(t testing.TB, shell string, libs []string, opts ...RunOption) *Session {
+	t.Helper()
+
+	cfg := runConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.hermetic == nil {
+		cfg.hermetic = NewEnv(t)
+	}
+
+	s, err := startSession(t, shell, libs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("failed to close session: %s", err)
+		}
+	})
+	return s
+}
+
+// startSession starts the shell of the session.
+func startSession(t testing.TB, name string, libs []string, cfg runConfig) (*Session, error) {
+	if cfg.useTTY {
+		return nil, errors.New("sessions do not support pseudo-terminals")
+	}
+
+	source, err := sourceLibs(libs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve libraries %v: %w", libs, err)
+	}
+	shell, ok := LookupShell(name)
+	if !ok {
+		return nil, fmt.Errorf("shell %s is not installed", name)
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	s := &Session{
+		shell: shell,
+		exits: make(chan int),
+		dir:   t.TempDir(),
+		token: hex.EncodeToString(token),
+	}
+
+	_, traced := coverageDir()
+	if traced {
+		source = coveragePrologue + source
+	}
+	// Sourcing must succeed before the loop starts, && chains of
+	// sourceLibs end with the loop.
+	script := source + fmt.Sprintf(sessionDriver, s.token, ShellQuote(s.dir))
+	args := append(append([]string{}, shell.Args...), "-c", script, "libtest")
+	s.cmd = exec.Command(shell.Path, args...)
+	setProcessGroup(s.cmd)
+	s.cmd.Env = cfg.hermetic.With(cfg.env...).Environ()
+	s.cmd.Dir = cfg.dir
+	s.cmd.Stderr = &s.stderr
+	PrintCmdDebug(t, s.cmd)
+
+	if s.stdin, err = s.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	s.recordCoverage = func() error { return nil }
+	if traced {
+		if s.recordCoverage, err = traceCoverage(s.cmd, t.TempDir()); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", shell, err)
+	}
+
+	go s.readExits(stdout)
+	return s, nil
+}
+
+// readExits parses exit markers written by the shell.
+func (s *Session) readExits(stdout io.Reader) {
+	defer close(s.exits)
+	prefix := "__libtest_exit_" + s.token + " "
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		code, err := strconv.Atoi(strings.TrimPrefix(line, prefix))
+		if err != nil {
+			continue
+		}
+		s.exits <- code
+	}
+}
+
+// Shell returns the shell of the session.
+func (s *Session) Shell() Shell {
+	return s.shell
+}
+
+// Run runs the script in a subshell of the session and returns the result.
+// WithArgs, WithEnv, WithDir, WithStdin and WithTimeout apply to the script.
+// Environment variables set with WithEnv are exported by the subshell.
+// On timeout, the shell of the session is killed along with all the
+// processes it started, and the session is unusable afterwards, as it is
+// after the shell exits.
+func (s *Session) Run(t *testing.T, script string, opts ...RunOption) Result {
+	t.Helper()
+
+	cfg := runConfig{}
+	cfg.apply(t, opts)
+	r, err := s.run(script, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// run sends the script to the shell and waits for its exit code.
+func (s *Session) run(script string, cfg runConfig) (Result, error) {
+	if cfg.useTTY || cfg.hermetic != nil || cfg.audit != nil {
+		return Result{}, errors.New("session calls do not support pseudo-terminals, hermetic environments or filesystem audits")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken != nil {
+		return Result{}, s.broken
+	}
+
+	stdin := []byte{}
+	if cfg.stdin != nil {
+		var err error
+		if stdin, err = ioutil.ReadAll(cfg.stdin); err != nil {
+			return Result{}, err
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.dir, "stdin"), stdin, 0644); err != nil {
+		return Result{}, err
+	}
+
+	frame := sessionPrelude(cfg) + script
+	if !strings.HasSuffix(frame, "\n") {
+		frame += "\n"
+	}
+	frame += "__libtest_end_" + s.token + "\n"
+
+	var timeout <-chan time.Time
+	if cfg.timeout > 0 {
+		timer := time.NewTimer(cfg.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	if _, err := io.WriteString(s.stdin, frame); err != nil {
+		s.wait()
+		s.broken = fmt.Errorf("%s: session exited: %w\nstderr: %s", s.shell, err, s.stderr.String())
+		return Result{}, s.broken
+	}
+
+	r := Result{Shell: s.shell}
+	select {
+	case code, ok := <-s.exits:
+		r.Duration = time.Since(start)
+		if !ok {
+			s.wait()
+			s.broken = fmt.Errorf("%s: session exited\nstderr: %s", s.shell, s.stderr.String())
+			return r, s.broken
+		}
+		r.ExitCode = code
+	case <-timeout:
+		running := groupCommands(s.cmd.Process.Pid)
+		killGroup(s.cmd.Process)
+		r.Duration = time.Since(start)
+		s.readOutput(&r)
+		s.broken = timeoutError(s.shell, cfg.timeout, script, cfg.args, running, r.Stdout, r.Stderr)
+		return r, s.broken
+	}
+	if err := s.readOutput(&r); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// readOutput reads stdout and stderr of the last call.
+func (s *Session) readOutput(r *Result) error {
+	stdout, err := ioutil.ReadFile(filepath.Join(s.dir, "stdout"))
+	if err != nil {
+		return err
+	}
+	stderr, err := ioutil.ReadFile(filepath.Join(s.dir, "stderr"))
+	if err != nil {
+		return err
+	}
+	r.Stdout, r.Stderr = string(stdout), string(stderr)
+	return nil
+}
+
+// sessionPrelude returns the commands setting positional parameters,
+// environment and working directory of a call.
+func sessionPrelude(cfg runConfig) string {
+	var b strings.Builder
+	b.WriteString("set --")
+	for _, arg := range cfg.args {
+		b.WriteString(" " + ShellQuote(arg))
+	}
+	b.WriteString("\n")
+
+	env := make(map[string]string)
+	for _, kv := range cfg.env {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "export %s=%s\n", key, ShellQuote(env[key]))
+	}
+
+	if cfg.dir != "" {
+		fmt.Fprintf(&b, "cd %s || exit 1\n", ShellQuote(cfg.dir))
+	}
+	return b.String()
+}
+
+// Close stops the shell of the session.
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.broken == nil {
+			s.broken = errors.New("session is closed")
+		}
+		// Shell exits at the end of its input.
+		s.stdin.Close()
+		for range s.exits {
+		}
+		var exitErr *exec.ExitError
+		if err := s.wait(); err != nil && !errors.As(err, &exitErr) {
+			s.closeErr = err
+			return
+		}
+		s.closeErr = s.recordCoverage()
+	})
+	return s.closeErr
+}
+
+// wait waits for the shell to exit, stderr of the session is complete
+// once it returns.
+func (s *Session) wait() error {
+	s.waitOnce.Do(func() {
+		s.waitErr = s.cmd.Wait()
+		killGroup(s.cmd.Process)
+	})
+	return s.waitErr
+}
@@ -0,0 +1,148 @@
+package libtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	for _, shell := range Shells(t) {
+		shell := shell
+		t.Run(shell.Name, func(t *testing.T) {
+			t.Parallel()
+			s := NewSession(t, shell.Name, []string{"dl"}, WithEnv("LIBTEST_SESSION=1"))
+
+			tests := []struct {
+				name   string
+				script string
+				opts   []RunOption
+				stdout string
+				stderr string
+				code   int
+			}{
+				{
+					name:   "args with spaces, quotes and newlines",
+					script: `printf '[%s]' "$@"`,
+					opts:   []RunOption{WithArgs("a b", `"q"`, "it's", "", "x\ny")},
+					stdout: "[a b][\"q\"][it's][][x\ny]",
+				},
+				{
+					name:   "session env",
+					script: `printf '%s' "${LIBTEST_SESSION}"`,
+					stdout: "1",
+				},
+				{
+					name:   "call env",
+					script: `sh -c 'printf "%s" "${LIBTEST_FOO}"'`,
+					opts:   []RunOption{WithEnv("LIBTEST_FOO=bar 'baz'")},
+					stdout: "bar 'baz'",
+				},
+				{
+					name:   "dir",
+					script: `pwd`,
+					opts:   []RunOption{WithDir("/")},
+					stdout: "/\n",
+				},
+				{
+					name:   "stdin",
+					script: `cat`,
+					opts:   []RunOption{WithStdin(strings.NewReader("from stdin"))},
+					stdout: "from stdin",
+				},
+				{
+					name:   "no stdin",
+					script: `cat`,
+				},
+				{
+					name:   "stderr and exit code",
+					script: `printf 'oops' >&2; printf 'out'; exit 3`,
+					stdout: "out",
+					stderr: "oops",
+					code:   3,
+				},
+				{
+					name:   "multi line script",
+					script: "f() {\n    return 4\n}\n\nf",
+					code:   4,
+				},
+				{
+					name:   "libraries are sourced",
+					script: `__libdl_is_function log_info && __libdl_GOARM "$@"`,
+					opts:   []RunOption{WithArgs("armv7l")},
+					stdout: "7",
+				},
+				{
+					name:   "state does not leak",
+					script: `printf '%s' "${LIBTEST_LEAK:-unset}" && __libdl_is_function f || printf ' no-f'; LIBTEST_LEAK=1; cd /`,
+					stdout: "unset no-f",
+				},
+				{
+					name:   "state does not leak again",
+					script: `printf '%s' "${LIBTEST_LEAK:-unset}" && __libdl_is_function f || printf ' no-f'; test "$(pwd)" != /`,
+					stdout: "unset no-f",
+				},
+			}
+			for _, tc := range tests {
+				r := s.Run(t, tc.script, tc.opts...)
+				assert.Equal(t, tc.stdout, r.Stdout, tc.name)
+				assert.Equal(t, tc.stderr, r.Stderr, tc.name)
+				assert.Equal(t, tc.code, r.ExitCode, tc.name)
+				assert.Equal(t, shell.Name, r.Shell.Name, tc.name)
+			}
+		})
+	}
+}
+
+func TestSessionMatchesRun(t *testing.T) {
+	s := NewSession(t, "sh", []string{"dl"})
+	for _, code := range []int{3, 22, 81} {
+		t.Run(fmt.Sprint(code), func(t *testing.T) {
+			want := Run(t, "sh", []string{"dl"}, `shlib_explain_error "$@"`, WithArgs(fmt.Sprint(code)))
+			got := s.Run(t, `shlib_explain_error "$@"`, WithArgs(fmt.Sprint(code)))
+			assert.Equal(t, want.Stdout, got.Stdout)
+			assert.Equal(t, want.Stderr, got.Stderr)
+			assert.Equal(t, want.ExitCode, got.ExitCode)
+		})
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		s := NewSession(t, "sh", nil)
+		start := time.Now()
+		_, err := s.run(`printf 'partial'; sleep 5`, runConfig{timeout: 200 * time.Millisecond, args: []string{"a"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out after 200ms")
+		assert.Contains(t, err.Error(), "script: printf 'partial'; sleep 5")
+		assert.Contains(t, err.Error(), `args: ["a"]`)
+		assert.Contains(t, err.Error(), "running: sleep 5")
+		assert.Contains(t, err.Error(), "stdout: partial")
+		require.NoError(t, s.Close())
+		assert.Less(t, int64(time.Since(start)), int64(4*time.Second))
+
+		_, err = s.run(`true`, runConfig{})
+		assert.Error(t, err, "session is unusable after a timeout")
+	})
+
+	t.Run("exited", func(t *testing.T) {
+		s := NewSession(t, "sh", []string{"../../utils/math.sh"})
+		_, err := s.run(`exit 0`, runConfig{})
+		require.NoError(t, err, "exit only exits the subshell")
+		_, err = s.run(`kill -9 $$`, runConfig{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "session exited")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		s := NewSession(t, "sh", nil)
+		require.NoError(t, s.Close())
+		require.NoError(t, s.Close())
+		_, err := s.run(`true`, runConfig{})
+		assert.EqualError(t, err, "session is closed")
+	})
+}
@@ -0,0 +1,176 @@
+package libtest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ShellsEnv is the environment variable which restricts the shells used by
+// tests. It is a comma separated list of shell names, like `bash,dash`.
+const ShellsEnv = "SHLIBS_TEST_SHELLS"
+
+// Shell is a shell installed on the host.
+type Shell struct {
+	// Name of the shell in the registry, like "bash" or "busybox-ash".
+	Name string
+	// Path of the shell executable.
+	Path string
+	// Args are arguments to be passed to the executable before any other
+	// arguments, like "ash" for busybox.
+	Args []string
+	// Version of the shell or "unknown" if it cannot be determined.
+	Version string
+}
+
+// String returns name and version of the shell.
+func (s Shell) String() string {
+	return s.Name + " " + s.Version
+}
+
+// shellSpec is a shell known to the registry.
+type shellSpec struct {
+	name    string
+	command string
+	args    []string
+}
+
+// knownShells is the list of shells tests are run against, in order.
+var knownShells = []shellSpec{
+	{name: "bash", command: "bash"},
+	{name: "sh", command: "sh"},
+	{name: "dash", command: "dash"},
+	{name: "zsh", command: "zsh"},
+	{name: "ksh", command: "ksh"},
+	{name: "mksh", command: "mksh"},
+	{name: "yash", command: "yash"},
+	{name: "busybox-ash", command: "busybox", args: []string{"ash"}},
+	{name: "posh", command: "posh"},
+}
+
+// shellVersionProbe prints version of the shell for shells which expose it
+// via a variable.
+const shellVersionProbe = `printf '%s' "${BASH_VERSION:-${ZSH_VERSION:-${YASH_VERSION:-${KSH_VERSION:-${POSH_VERSION:-}}}}}"`
+
+var busyboxVersionRegex = regexp.MustCompile(`BusyBox (v[^\s]+)`)
+
+var (
+	detectOnce     sync.Once
+	detectedShells map[string]Shell
+)
+
+// detectShells returns the shells from the registry which are installed on
+// the host. Detection is only done once per test binary.
+func detectShells() map[string]Shell {
+	detectOnce.Do(func() {
+		detectedShells = make(map[string]Shell)
+		for _, spec := range knownShells {
+			if shell, ok := detectShell(spec); ok {
+				detectedShells[spec.name] = shell
+			}
+		}
+	})
+	return detectedShells
+}
+
+// detectShell looks up the shell in PATH and determines its version.
+func detectShell(spec shellSpec) (Shell, bool) {
+	path, err := exec.LookPath(spec.command)
+	if err != nil {
+		return Shell{}, false
+	}
+
+	shell := Shell{
+		Name:    spec.name,
+		Path:    path,
+		Args:    spec.args,
+		Version: "unknown",
+	}
+
+	if spec.command == "busybox" {
+		// busybox prints its version as the first line of usage.
+		out, _ := exec.Command(path).CombinedOutput()
+		if m := busyboxVersionRegex.FindSubmatch(out); m != nil {
+			shell.Version = string(m[1])
+		}
+		return shell, true
+	}
+
+	args := append(append([]string{}, spec.args...), "-c", shellVersionProbe)
+	if out, err := exec.Command(path, args...).Output(); err == nil && len(out) > 0 {
+		shell.Version = strings.TrimSpace(string(out))
+	}
+
+	// sh is usually a symlink to some other shell, include it in the version.
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		if base := filepath.Base(target); base != filepath.Base(path) {
+			shell.Version += " (" + base + ")"
+		}
+	}
+	return shell, true
+}
+
+// requestedShells returns the names of the shells tests should run against.
+// If ShellsEnv is set, only shells listed in it are used, otherwise all the
+// shells in the registry.
+func requestedShells() []string {
+	var names []string
+	if v := os.Getenv(ShellsEnv); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	for _, spec := range knownShells {
+		names = append(names, spec.name)
+	}
+	return names
+}
+
+// LookupShell returns the installed shell with the given name. Names not in
+// the registry are looked up in PATH as executables.
+func LookupShell(name string) (Shell, bool) {
+	if shell, ok := detectShells()[name]; ok {
+		return shell, true
+	}
+	for _, spec := range knownShells {
+		if spec.name == name {
+			return Shell{}, false
+		}
+	}
+	return detectShell(shellSpec{name: name, command: name})
+}
+
+// Shells returns the installed shells tests should run against. Shells which
+// are requested but not installed are reported as skipped subtests of t,
+// instead of failing the test. Versions of the shells used are logged.
+//
+//	for _, shell := range libtest.Shells(t) {
+//		t.Run(shell.Name, func(t *testing.T) {
+//			r := libtest.Run(t, shell.Name, []string{"dl"}, "__libdl_GOOS")
+//		})
+//	}
+func Shells(t *testing.T) []Shell {
+	t.Helper()
+
+	var shells []Shell
+	for _, name := range requestedShells() {
+		shell, ok := LookupShell(name)
+		if !ok {
+			t.Run(name, func(t *testing.T) {
+				t.Skipf("shell %s is not installed", name)
+			})
+			continue
+		}
+		t.Logf("using shell %s (%s)", shell, shell.Path)
+		shells = append(shells, shell)
+	}
+	return shells
+}
@@ -0,0 +1,48 @@
+package libtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestedShells(t *testing.T) {
+	tests := map[string]struct {
+		env    string
+		expect []string
+	}{
+		"default":    {expect: []string{"bash", "sh", "dash", "zsh", "ksh", "mksh", "yash", "busybox-ash", "posh"}},
+		"restricted": {env: "bash,dash", expect: []string{"bash", "dash"}},
+		"spaces":     {env: " bash , ,dash,", expect: []string{"bash", "dash"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(ShellsEnv, tc.env)
+			assert.Equal(t, tc.expect, requestedShells())
+		})
+	}
+}
+
+func TestShells(t *testing.T) {
+	t.Setenv(ShellsEnv, "sh,no-such-shell")
+	shells := Shells(t)
+	if assert.Len(t, shells, 1) {
+		assert.Equal(t, "sh", shells[0].Name)
+		assert.NotEmpty(t, shells[0].Path)
+		assert.NotEmpty(t, shells[0].Version)
+	}
+}
+
+func TestLookupShell(t *testing.T) {
+	t.Run("unknown", func(t *testing.T) {
+		_, ok := LookupShell("no-such-shell")
+		assert.False(t, ok)
+	})
+
+	t.Run("executable", func(t *testing.T) {
+		shell, ok := LookupShell("sh")
+		assert.True(t, ok)
+		r := Run(t, "sh", nil, "true")
+		assert.Equal(t, shell, r.Shell)
+	})
+}
@@ -6,11 +6,11 @@ package logger
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tprasadtp/shlibs/internal/apollo"
@@ -19,21 +19,21 @@ import (
 
 type loggerTestTable struct {
 	name   string
-	shell  string
+	shell  libtest.Shell
 	level  int
 	format string
 	output string
 	color  bool
 }
 
-func generateTestTable() []loggerTestTable {
+func generateTestTable(t *testing.T) []loggerTestTable {
 	var testCases []loggerTestTable
-	for _, shell := range libtest.SupportedShells() {
+	for _, shell := range libtest.Shells(t) {
 		for _, format := range []string{"pretty", "full", "long", "fallback"} {
 			for _, output := range []string{"stderr", "stdout", "default"} {
 				for _, color := range []bool{true, false} {
 					for _, level := range []int{0, 10, 20, 30, 35, 40, 50} {
-						name := fmt.Sprintf("%s-format-%s-color-%t-output-%s-level-%d", shell, format, color, output, level)
+						name := fmt.Sprintf("%s-format-%s-color-%t-output-%s-level-%d", shell.Name, format, color, output, level)
 						tc := loggerTestTable{
 							name:   name,
 							shell:  shell,
@@ -52,22 +52,30 @@ func generateTestTable() []loggerTestTable {
 }
 
 func TestVersionFormats(t *testing.T) {
-	libtest.AssertCommandAvailable(t, "faketime")
-	libtest.AssertShellsAvailable(t)
+	// timestamps are pinned by a fake date in the sandbox, demo.sh only
+	// needs dirname from the host.
+	sb := libtest.NewSandbox(t, "dirname")
+	sb.FakeDate(libtest.FixedClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
 
-	// disable colored diff, as we are printing colors already
-	g := apollo.New(t, apollo.WithDiffEngine(apollo.ClassicDiff))
+	// disable colored diff, as we are printing colors already.
+	// golden files are compared with LF line endings, so that checkouts with
+	// core.autocrlf do not break the fixtures.
+	g := apollo.New(t,
+		apollo.WithDiffEngine(apollo.ClassicDiff),
+		apollo.WithLineEndings(apollo.LF),
+	)
 
-	testCases := generateTestTable()
+	testCases := generateTestTable(t)
 	t.Logf("Total test cases: %d", len(testCases))
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			cmd := exec.Command("faketime", "-f", "2000-01-01 00:00:00", tc.shell, "demo.sh")
-			cmd.Env = append(os.Environ(),
+			cmd := exec.Command(tc.shell.Path, append(append([]string{}, tc.shell.Args...), "demo.sh")...)
+			cmd.Env = libtest.NewEnv(t,
+				"PATH="+sb.BinDir(),
 				"TZ=UTC",
 				fmt.Sprintf("LOG_FMT=%s", tc.format),
 				fmt.Sprintf("LOG_LVL=%s", strconv.Itoa(tc.level)),
-			)
+			).Environ()
 			libtest.PrintCmdDebug(t, cmd)
 
 			switch strings.ToLower(tc.output) {
@@ -121,3 +129,27 @@ func TestVersionFormats(t *testing.T) {
 		})
 	}
 }
+
+// Each log line in full format has the time at which it was logged.
+func TestTimestampAdvances(t *testing.T) {
+	for _, shell := range libtest.Shells(t) {
+		t.Run(shell.Name, func(t *testing.T) {
+			sb := libtest.NewSandbox(t)
+			date := sb.FakeDate(libtest.Clock{
+				Start: time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC),
+				Step:  time.Second,
+			})
+
+			r := libtest.Run(t, shell.Name, []string{"logger"}, `log_info "first"; log_warning "second"; log_error "third"`,
+				libtest.WithSandbox(sb),
+				libtest.WithEnv("TZ=UTC", "LOG_FMT=full", "NO_COLOR=1"),
+			)
+			assert.Equal(t, 0, r.ExitCode)
+			assert.Empty(t, r.Stdout)
+			assert.Equal(t, "1999-12-31 23:59:59+00:00 [INFO    ] first \n"+
+				"2000-01-01 00:00:00+00:00 [WARNING ] second \n"+
+				"2000-01-01 00:00:01+00:00 [ERROR   ] third \n", r.Stderr)
+			assert.Len(t, date.Calls(), 3)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+//go:build linux
+// +build linux
+
+package logger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// Test color auto-detection, which enables colors only if both stdout and
+// stderr are terminals.
+func TestColorAutoDetection(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  bool
+		stderr  bool
+		env     []string
+		colored bool
+	}{
+		{name: "tty", stdout: true, stderr: true, colored: true},
+		{name: "tty-stdout-only", stdout: true},
+		{name: "tty-stderr-only", stderr: true},
+		{name: "pipe"},
+		{name: "tty-no-color", stdout: true, stderr: true, env: []string{"NO_COLOR=1"}},
+		{name: "tty-clicolor-0", stdout: true, stderr: true, env: []string{"CLICOLOR=0"}},
+		{name: "tty-term-dumb", stdout: true, stderr: true, env: []string{"TERM=dumb"}},
+		{name: "pipe-clicolor-force", env: []string{"CLICOLOR_FORCE=1"}, colored: true},
+	}
+
+	for _, shell := range libtest.Shells(t) {
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s-%s", shell.Name, tc.name), func(t *testing.T) {
+				r := libtest.RunPTY(t, shell.Name, []string{"logger"}, `log_error "hello"`,
+					libtest.WithTTY(tc.stdout, tc.stderr),
+					libtest.WithEnv(tc.env...),
+				)
+				assert.Equal(t, 0, r.ExitCode)
+				assert.Empty(t, r.Stdout)
+				assert.Contains(t, r.Stderr, "hello")
+				if tc.colored {
+					assert.Contains(t, r.Stderr, "\x1b[38;5;197m")
+					assert.Contains(t, r.Stderr, "•")
+				} else {
+					assert.NotContains(t, r.Stderr, "\x1b[")
+					assert.Contains(t, r.Stderr, "[ERROR   ]")
+				}
+			})
+		}
+	}
+}
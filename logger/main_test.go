@@ -0,0 +1,13 @@
+package logger
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tprasadtp/shlibs/internal/apollo"
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(libtest.ReportCoverage(apollo.Run(m)))
+}
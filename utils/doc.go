@@ -0,0 +1,5 @@
+// Package utils holds shell utility libraries like math.sh.
+// This package is not meant to be used in go, but in shells instead.
+// This go package exists to extensively unit test the shellscripts.
+
+package utils
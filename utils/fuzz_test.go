@@ -0,0 +1,29 @@
+//go:build go1.18
+// +build go1.18
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+// isIntegerOracle returns 0 if the input is a non empty string of ASCII
+// digits.
+func isIntegerOracle(s string) (string, int) {
+	if s == "" {
+		return "", 1
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return "", 1
+		}
+	}
+	return "", 0
+}
+
+func FuzzIsInteger(f *testing.F) {
+	libtest.FuzzFunction(f, []string{"math"}, "math__is_integer", isIntegerOracle,
+		"", "0", "42", "007", "-1", "+1", "1.5", "1e3", " 1", "1\n", "٣")
+}
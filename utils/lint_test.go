@@ -0,0 +1,11 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func TestLint(t *testing.T) {
+	libtest.Lint(t, "math", "utils")
+}
@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"os"
+	"testing"
+
+	"github.com/tprasadtp/shlibs/internal/libtest"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(libtest.ReportCoverage(m.Run()))
+}